@@ -1,3 +1,427 @@
 package main
 
-func main() {}
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kotsmile/go-vote/blockchain"
+	"github.com/kotsmile/go-vote/node"
+)
+
+const DefaultNodeAddr = "localhost:8001"
+
+const usage = `usage: go-vote [-node addr] <command> [args]
+
+commands:
+  voting create --title <title>          create new voting
+      [--kind single|multi|ranked] [--option <option>]... [--max <n>]
+      [--start-height <n>] [--end-height <n>]
+      [--start-time <rfc3339>] [--end-time <rfc3339>]
+      [--voter <address>... | --merkle-root <root> | --registry <id> |
+       --electorate <genesis electorate>]
+      [--duplicates first|last|forbid]
+  voting close <id>                      close voting created by node
+  vote cast --voting <id> --yes|--no     cast vote for yes/no voting
+  vote cast --voting <id> --choice <option>...
+                                         cast ballot; options are given by
+                                         name or index, in order of
+                                         preference for ranked votings
+      [--proof <hash>]...                merkle proof of node address
+  votings list                           list all votings
+  results <id>                           show results of voting
+  chain show                             print all blocks of chain
+  block <nonce|hash>                     print single block
+  peers                                  list peers of node
+  status                                 show node status
+  electorate root <address>...           merkle root of addresses
+  electorate proof <address> <electorate address>...
+                                         merkle proof of address
+`
+
+func main() {
+	flags := flag.NewFlagSet("go-vote", flag.ExitOnError)
+	nodeAddr := flags.String("node", DefaultNodeAddr, "address of node api")
+	flags.Usage = func() { fmt.Fprint(os.Stderr, usage) }
+	flags.Parse(os.Args[1:])
+
+	client := node.NewApiClient(*nodeAddr)
+	if err := run(client, flags.Args()); err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+var ErrUsage = errors.New("invalid usage")
+
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *stringList) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
+func run(client *node.ApiClient, args []string) error {
+	if len(args) < 1 {
+		fmt.Fprint(os.Stderr, usage)
+		return ErrUsage
+	}
+
+	switch args[0] {
+	case "voting":
+		if len(args) >= 3 && args[1] == "close" {
+			return closeVoting(client, args[2])
+		}
+		if len(args) < 2 || args[1] != "create" {
+			fmt.Fprint(os.Stderr, usage)
+			return ErrUsage
+		}
+		return createVoting(client, args[2:])
+	case "vote":
+		if len(args) < 2 || args[1] != "cast" {
+			fmt.Fprint(os.Stderr, usage)
+			return ErrUsage
+		}
+		return castVote(client, args[2:])
+	case "votings":
+		if len(args) < 2 || args[1] != "list" {
+			fmt.Fprint(os.Stderr, usage)
+			return ErrUsage
+		}
+		return listVotings(client)
+	case "results":
+		if len(args) < 2 {
+			fmt.Fprint(os.Stderr, usage)
+			return ErrUsage
+		}
+		return showResults(client, args[1])
+	case "chain":
+		if len(args) < 2 || args[1] != "show" {
+			fmt.Fprint(os.Stderr, usage)
+			return ErrUsage
+		}
+		return showChain(client)
+	case "block":
+		if len(args) < 2 {
+			fmt.Fprint(os.Stderr, usage)
+			return ErrUsage
+		}
+		return showBlock(client, args[1])
+	case "peers":
+		return listPeers(client)
+	case "electorate":
+		if len(args) >= 3 && args[1] == "root" {
+			return electorateRoot(args[2:])
+		}
+		if len(args) >= 4 && args[1] == "proof" {
+			return electorateProof(args[2], args[3:])
+		}
+		fmt.Fprint(os.Stderr, usage)
+		return ErrUsage
+	case "status":
+		return showStatus(client)
+	default:
+		fmt.Fprint(os.Stderr, usage)
+		return ErrUsage
+	}
+}
+
+func createVoting(client *node.ApiClient, args []string) error {
+	flags := flag.NewFlagSet("voting create", flag.ExitOnError)
+	title := flags.String("title", "", "title of voting")
+	kind := flags.String("kind", string(blockchain.SingleChoiceVoting), "kind of voting: single, multi or ranked")
+	maxChoices := flags.Int("max", 0, "max number of approved options for multi voting")
+	var options stringList
+	flags.Var(&options, "option", "option of voting, repeat for every option; yes/no by default")
+	startHeight := flags.Uint64("start-height", 0, "first block accepting votes")
+	endHeight := flags.Uint64("end-height", 0, "last block accepting votes")
+	startTime := flags.String("start-time", "", "time voting opens, RFC3339")
+	endTime := flags.String("end-time", "", "time voting ends, RFC3339")
+	var voters stringList
+	flags.Var(&voters, "voter", "eligible voter address, repeat for every voter")
+	merkleRoot := flags.String("merkle-root", "", "merkle root of eligible addresses")
+	registry := flags.String("registry", "", "registry voting whose voters are eligible")
+	genesisElectorate := flags.String("electorate", "", "name of electorate declared in genesis")
+	duplicates := flags.String("duplicates", string(blockchain.FirstVoteWins), "which vote counts when address votes twice: first, last or forbid")
+	flags.Parse(args)
+
+	if *title == "" {
+		return fmt.Errorf("--title is required")
+	}
+
+	start, err := parseTime(*startTime)
+	if err != nil {
+		return fmt.Errorf("invalid --start-time: %v", err)
+	}
+	end, err := parseTime(*endTime)
+	if err != nil {
+		return fmt.Errorf("invalid --end-time: %v", err)
+	}
+
+	var electorate *blockchain.Electorate
+	if len(voters) > 0 || *merkleRoot != "" || *registry != "" || *genesisElectorate != "" {
+		electorate = &blockchain.Electorate{
+			Addresses:  toAddresses(voters),
+			MerkleRoot: *merkleRoot,
+			Registry:   *registry,
+			Genesis:    *genesisElectorate,
+		}
+	}
+
+	id, err := client.CreateVoting(node.CreateVotingRequest{
+		Title:      *title,
+		Kind:       blockchain.VotingKind(*kind),
+		Options:    options,
+		MaxChoices: *maxChoices,
+
+		StartHeight: *startHeight,
+		EndHeight:   *endHeight,
+		StartTime:   start,
+		EndTime:     end,
+
+		Electorate: electorate,
+		Duplicates: blockchain.DuplicatePolicy(*duplicates),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create voting: %v", err)
+	}
+
+	fmt.Println(id)
+	return nil
+}
+
+func parseTime(value string) (int64, error) {
+	if value == "" {
+		return 0, nil
+	}
+
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return 0, err
+	}
+
+	return t.Unix(), nil
+}
+
+func closeVoting(client *node.ApiClient, voting string) error {
+	id, err := client.CloseVoting(voting)
+	if err != nil {
+		return fmt.Errorf("failed to close voting: %v", err)
+	}
+
+	fmt.Println(id)
+	return nil
+}
+
+func castVote(client *node.ApiClient, args []string) error {
+	flags := flag.NewFlagSet("vote cast", flag.ExitOnError)
+	voting := flags.String("voting", "", "id of voting")
+	yes := flags.Bool("yes", false, "vote for")
+	no := flags.Bool("no", false, "vote against")
+	var choices stringList
+	flags.Var(&choices, "choice", "chosen option by name or index, repeat for several options")
+	var proof stringList
+	flags.Var(&proof, "proof", "merkle proof hash, repeat for every hash")
+	flags.Parse(args)
+
+	if *voting == "" {
+		return fmt.Errorf("--voting is required")
+	}
+
+	req := node.CastVoteRequest{
+		Voting: *voting,
+		Value:  *yes,
+		Proof:  proof,
+	}
+	if len(choices) > 0 {
+		if *yes || *no {
+			return fmt.Errorf("--choice can not be combined with --yes or --no")
+		}
+
+		votingRes, err := client.Voting(*voting)
+		if err != nil {
+			return fmt.Errorf("failed to get voting: %v", err)
+		}
+
+		req.Choices, err = resolveChoices(votingRes.Options, choices)
+		if err != nil {
+			return err
+		}
+	} else if *yes == *no {
+		return fmt.Errorf("exactly one of --yes, --no or --choice is required")
+	}
+
+	id, err := client.CastVote(req)
+	if err != nil {
+		return fmt.Errorf("failed to cast vote: %v", err)
+	}
+
+	fmt.Println(id)
+	return nil
+}
+
+func resolveChoices(options []string, choices []string) ([]int, error) {
+	res := make([]int, 0, len(choices))
+
+outer:
+	for _, choice := range choices {
+		for i, option := range options {
+			if option == choice {
+				res = append(res, i)
+				continue outer
+			}
+		}
+
+		index, err := strconv.Atoi(choice)
+		if err != nil || index < 0 || index >= len(options) {
+			return nil, fmt.Errorf("unknown option %s", choice)
+		}
+		res = append(res, index)
+	}
+
+	return res, nil
+}
+
+func listVotings(client *node.ApiClient) error {
+	votings, err := client.Votings()
+	if err != nil {
+		return fmt.Errorf("failed to list votings: %v", err)
+	}
+
+	for _, voting := range votings {
+		fmt.Printf("%s #%d %s [%s: %s] %s\n", voting.ID, voting.Nonce, voting.Title,
+			voting.Kind, strings.Join(voting.Options, ", "), voting.Status)
+	}
+
+	return nil
+}
+
+func showResults(client *node.ApiClient, voting string) error {
+	results, err := client.Results(voting)
+	if err != nil {
+		return fmt.Errorf("failed to get results: %v", err)
+	}
+
+	tally := results.Tally
+
+	fmt.Printf("voting: %s (%s)\n", results.Voting.Title, results.Voting.Kind)
+	fmt.Printf("status: %s; final: %t; finalized: %t\n", tally.Status, tally.Final, tally.Finalized)
+	fmt.Printf("ballots: %d; invalid: %d\n", tally.Ballots, tally.Invalid)
+	for i, option := range tally.Options {
+		fmt.Printf("  %d. %s: %d\n", i, option, tally.Counts[i])
+	}
+	for i, round := range tally.Rounds {
+		fmt.Printf("round %d: %v\n", i+1, round)
+	}
+
+	if winner, ok := tally.WinnerOption(); ok {
+		fmt.Printf("winner: %s\n", winner)
+	} else {
+		fmt.Println("winner: none")
+	}
+
+	return nil
+}
+
+func showChain(client *node.ApiClient) error {
+	blocks, err := client.Chain()
+	if err != nil {
+		return fmt.Errorf("failed to get chain: %v", err)
+	}
+
+	for _, block := range blocks {
+		fmt.Println(block.String())
+	}
+
+	return nil
+}
+
+func showBlock(client *node.ApiClient, ref string) error {
+	var err error
+	var block blockchain.Block
+
+	if nonce, convErr := strconv.Atoi(ref); convErr == nil {
+		block, err = client.Block(nonce)
+	} else {
+		block, err = client.BlockByHash(ref)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get block: %v", err)
+	}
+
+	fmt.Println(block.String())
+	return nil
+}
+
+func listPeers(client *node.ApiClient) error {
+	peers, err := client.Peers()
+	if err != nil {
+		return fmt.Errorf("failed to list peers: %v", err)
+	}
+
+	for _, peer := range peers {
+		fmt.Println(peer)
+	}
+
+	return nil
+}
+
+func showStatus(client *node.ApiClient) error {
+	status, err := client.Status()
+	if err != nil {
+		return fmt.Errorf("failed to get status: %v", err)
+	}
+
+	fmt.Printf("name: %s\n", status.Name)
+	fmt.Printf("chain id: %s\n", status.ChainID)
+	fmt.Printf("genesis: %s\n", status.GenesisHash)
+	fmt.Printf("address: %s\n", status.Address)
+	fmt.Printf("listen: %s\n", status.ListenAddr)
+	fmt.Printf("height: %d\n", status.Height)
+	fmt.Printf("finalized: %d\n", status.Finalized)
+	fmt.Printf("last block: %s\n", status.LastBlockHash)
+	fmt.Printf("state root: %s\n", status.StateRoot)
+	fmt.Printf("votings: %d\n", status.Votings)
+	fmt.Printf("pending transactions: %d\n", status.Pending)
+	fmt.Printf("peers: %d\n", status.Peers)
+	fmt.Printf("consensus: %s\n", status.Consensus)
+	fmt.Printf("hash rate: %d H/s\n", status.HashRate)
+
+	return nil
+}
+
+func toAddresses(values []string) []blockchain.Address {
+	addresses := make([]blockchain.Address, 0, len(values))
+	for _, value := range values {
+		addresses = append(addresses, blockchain.Address(value))
+	}
+
+	return addresses
+}
+
+func electorateRoot(addresses []string) error {
+	fmt.Println(blockchain.AddressMerkleRoot(toAddresses(addresses)))
+	return nil
+}
+
+func electorateProof(address string, addresses []string) error {
+	proof, ok := blockchain.AddressMerkleProof(toAddresses(addresses), blockchain.Address(address))
+	if !ok {
+		return fmt.Errorf("address %s is not in electorate", address)
+	}
+
+	for _, hash := range proof {
+		fmt.Printf("--proof %s ", hash)
+	}
+	fmt.Println()
+
+	return nil
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+type Config struct {
+	Name       string   `json:"name"`
+	ListenAddr string   `json:"listenAddr"`
+	ApiAddr    string   `json:"apiAddr"`
+	DataDir    string   `json:"dataDir"`
+	Peers      []string `json:"peers"`
+	WalletPath string   `json:"walletPath"`
+	Verbose    bool     `json:"verbose"`
+	Mine       bool     `json:"mine"`
+	// GenesisPath is path to genesis of network, default network is used
+	// when empty
+	GenesisPath string `json:"genesisPath"`
+	// Secure encrypts peer connections, all peers must enable it
+	Secure bool `json:"secure"`
+	// Codec is wire format of peer messages, "framed" or "json"
+	Codec string `json:"codec"`
+	// Compress compresses large framed messages
+	Compress bool `json:"compress"`
+}
+
+func DefaultConfig() Config {
+	return Config{
+		Name:        "node",
+		ListenAddr:  ":3001",
+		ApiAddr:     "localhost:8001",
+		DataDir:     "data",
+		Peers:       []string{},
+		WalletPath:  "",
+		Verbose:     false,
+		Mine:        false,
+		GenesisPath: "",
+		Secure:      false,
+		Codec:       "framed",
+		Compress:    false,
+	}
+}
+
+func (c Config) StorePath() string {
+	return filepath.Join(c.DataDir, "blocks")
+}
+
+func (c Config) WalletFilepath() string {
+	if c.WalletPath != "" {
+		return c.WalletPath
+	}
+
+	return filepath.Join(c.DataDir, "wallet")
+}
+
+func LoadConfig(path string) (Config, error) {
+	config := DefaultConfig()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Config{}, fmt.Errorf("failed to read config %s: %v", path, err)
+	}
+	if err := json.Unmarshal(data, &config); err != nil {
+		return Config{}, fmt.Errorf("failed to deserialize config %s: %v", path, err)
+	}
+
+	return config, nil
+}
+
+// ParseConfig loads config file given by -config and applies all flags
+// explicitly set on command line on top of it.
+func ParseConfig(args []string) (Config, error) {
+	flags := flag.NewFlagSet("go-vote-node", flag.ContinueOnError)
+
+	configPath := flags.String("config", "", "path to json config file")
+	name := flags.String("name", "", "name of node used in logs")
+	listenAddr := flags.String("listen", "", "p2p listen address")
+	apiAddr := flags.String("api", "", "control api address; empty string disables api")
+	dataDir := flags.String("data-dir", "", "directory for chain and wallet files")
+	peers := flags.String("peers", "", "comma separated list of bootstrap peers")
+	walletPath := flags.String("wallet", "", "path to wallet file")
+	verbose := flags.Bool("verbose", false, "log received blocks")
+	mine := flags.Bool("mine", false, "seal pending transactions into blocks")
+	genesisPath := flags.String("genesis", "", "path to genesis json of network")
+	secure := flags.Bool("secure", false, "encrypt peer connections with tls")
+	codec := flags.String("codec", "", "wire format of peer messages: framed or json")
+	compress := flags.Bool("compress", false, "compress large framed messages")
+
+	if err := flags.Parse(args); err != nil {
+		return Config{}, err
+	}
+
+	config := DefaultConfig()
+	if *configPath != "" {
+		var err error
+		config, err = LoadConfig(*configPath)
+		if err != nil {
+			return Config{}, err
+		}
+	}
+
+	flags.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "name":
+			config.Name = *name
+		case "listen":
+			config.ListenAddr = *listenAddr
+		case "api":
+			config.ApiAddr = *apiAddr
+		case "data-dir":
+			config.DataDir = *dataDir
+		case "peers":
+			config.Peers = splitPeers(*peers)
+		case "wallet":
+			config.WalletPath = *walletPath
+		case "verbose":
+			config.Verbose = *verbose
+		case "mine":
+			config.Mine = *mine
+		case "genesis":
+			config.GenesisPath = *genesisPath
+		case "secure":
+			config.Secure = *secure
+		case "codec":
+			config.Codec = *codec
+		case "compress":
+			config.Compress = *compress
+		}
+	})
+
+	if config.ListenAddr == "" {
+		return Config{}, errors.New("listen address is required")
+	}
+
+	return config, nil
+}
+
+func splitPeers(peers string) []string {
+	res := make([]string, 0)
+	for _, peer := range strings.Split(peers, ",") {
+		peer = strings.TrimSpace(peer)
+		if peer != "" {
+			res = append(res, peer)
+		}
+	}
+
+	return res
+}
@@ -1,22 +1,166 @@
 package main
 
-// **Tech Stack**: Go (crypto/sha256, net, fmt)
-// - **Steps**:
-//     1. Create a blockchain structure where each block represents a vote.
-//     2. Use `crypto/sha256` to hash block data and create cryptographic links between blocks.
-//     3. Implement basic proof-of-work by making nodes solve a computational problem before adding new blocks.
-//     4. Use `net` to implement peer-to-peer communication, allowing nodes to broadcast new blocks to each other.
-//     5. Build a simple CLI to interact with the blockchain (e.g., cast votes, view results).
-// - **Enhancements**:
-//     - Implement a consensus algorithm to resolve chain conflicts and ensure tamper-proof voting.
-//     - Add zero-knowledge proofs for anonymous voting.
-
-// - Exists two types of transactions
-//   1. Create new voting
-//     - title
-//     - voteId
-//   2. Cast vote
-//     - voteId
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+
+	"github.com/kotsmile/go-vote/blockchain"
+	"github.com/kotsmile/go-vote/node"
+	"github.com/kotsmile/go-vote/p2p"
+)
 
 func main() {
+	config, err := ParseConfig(os.Args[1:])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to parse config: %v\n", err)
+		os.Exit(2)
+	}
+
+	if err := run(config); err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(config Config) error {
+	if err := os.MkdirAll(config.DataDir, 0755); err != nil {
+		return fmt.Errorf("failed to create data dir %s: %v", config.DataDir, err)
+	}
+
+	wallet, err := loadOrCreateWallet(config.WalletFilepath())
+	if err != nil {
+		return err
+	}
+
+	store, err := blockchain.OpenFileStore(config.StorePath())
+	if err != nil {
+		return fmt.Errorf("failed to open block store: %v", err)
+	}
+
+	// chain is validated and recovered when node starts
+	chain, err := blockchain.NewChainFromStore(store)
+	if err != nil {
+		store.Close()
+		return fmt.Errorf("failed to load chain: %v", err)
+	}
+
+	transport, err := newTransport(config, wallet)
+	if err != nil {
+		store.Close()
+		return err
+	}
+
+	n, err := node.NewNodeWithChain(chain, transport, wallet)
+	if err != nil {
+		store.Close()
+		return err
+	}
+	n.WithName(config.Name).
+		WithMining(config.Mine).
+		WithQuarantinePath(config.StorePath())
+
+	if config.GenesisPath != "" {
+		genesis, err := blockchain.NewGenesisFromFile(config.GenesisPath)
+		if err != nil {
+			store.Close()
+			return fmt.Errorf("failed to load genesis: %v", err)
+		}
+		if _, err := n.WithGenesis(genesis); err != nil {
+			store.Close()
+			return err
+		}
+	}
+
+	errCh := make(chan error, 2)
+	go func() {
+		errCh <- n.Start(config.Verbose)
+	}()
+
+	if config.ApiAddr != "" {
+		go func() {
+			errCh <- n.ServeApi(config.ApiAddr)
+		}()
+	}
+
+	for _, peer := range config.Peers {
+		if err := n.Connect(peer); err != nil {
+			n.Log(fmt.Sprintf("failed to connect bootstrap peer %s: %v", peer, err))
+		}
+	}
+
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
+
+	select {
+	case sig := <-sigCh:
+		n.Log(fmt.Sprintf("received %s; shutting down", sig))
+	case err := <-errCh:
+		if err != nil {
+			n.Log(fmt.Sprintf("stopped: %v", err))
+		}
+	}
+
+	if err := n.Stop(); err != nil {
+		return fmt.Errorf("failed to stop node: %v", err)
+	}
+
+	return nil
+}
+
+func loadOrCreateWallet(path string) (blockchain.Wallet, error) {
+	wallet, err := blockchain.NewWalletFromFile(path)
+	if err == nil {
+		return wallet, nil
+	}
+	if _, statErr := os.Stat(path); !errors.Is(statErr, os.ErrNotExist) {
+		return "", err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return "", fmt.Errorf("failed to create wallet dir: %v", err)
+	}
+
+	wallet = blockchain.NewRandomWallet()
+	if err := wallet.SaveFile(path); err != nil {
+		return "", err
+	}
+
+	return wallet, nil
+}
+
+func newTransport(config Config, wallet blockchain.Wallet) (p2p.Transport, error) {
+	codec, err := newCodec(config)
+	if err != nil {
+		return nil, err
+	}
+
+	if !config.Secure {
+		transport := p2p.NewTcpTransport(config.ListenAddr)
+		transport.Codec = codec
+		return transport, nil
+	}
+
+	cert, err := node.NewWalletCertificate(wallet)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create tls certificate: %v", err)
+	}
+
+	transport := p2p.NewSecureTcpTransport(config.ListenAddr, cert)
+	transport.Codec = codec
+	return transport, nil
+}
+
+func newCodec(config Config) (p2p.CodecFunc, error) {
+	switch config.Codec {
+	case "", "framed":
+		return p2p.NewFramedCodecFunc(p2p.DefaultMaxFrameSize, config.Compress), nil
+	case "json":
+		return p2p.NewJsonCodec, nil
+	default:
+		return nil, fmt.Errorf("unknown codec %q", config.Codec)
+	}
 }
@@ -4,10 +4,11 @@ import (
 	"bytes"
 	"encoding/hex"
 	"fmt"
+	"time"
 )
 
 const (
-	DefaultDifficulty = 2
+	DefaultDifficulty = 16
 	ZeroHash          = "0000000000000000000000000000000000000000000000000000000000000000"
 )
 
@@ -20,7 +21,9 @@ type Block struct {
 	signer Wallet
 
 	SignBlockData
-	Signature Signature `json:"signature"`
+	// Transactions are committed to block hash by TxRoot
+	Transactions []Transaction `json:"transactions,omitempty"`
+	Signature    Signature     `json:"signature"`
 }
 
 var GenesisBlock = Block{
@@ -48,19 +51,38 @@ func NewBlock(prevBlock Block, signer Wallet, data []byte) (Block, error) {
 				From:          addr,
 				Data:          data,
 				Difficulty:    DefaultDifficulty,
+				Timestamp:     time.Now().Unix(),
 			},
 		},
 	}, nil
 }
 
+func NewBlockWithTransactions(prevBlock Block, signer Wallet, txs []Transaction) (Block, error) {
+	block, err := NewBlock(prevBlock, signer, nil)
+	if err != nil {
+		return Block{}, err
+	}
+
+	txRoot, err := TransactionsRoot(txs)
+	if err != nil {
+		return Block{}, fmt.Errorf("failed to get transactions root: %v", err)
+	}
+
+	block.Transactions = txs
+	block.TxRoot = txRoot
+	return block, nil
+}
+
 func (b Block) String() string {
 	s := fmt.Sprintf("Block #%d\n", b.Nonce) +
 		fmt.Sprintf("\tprevBlockHash: %s\n", b.PrevBlockHash) +
 		fmt.Sprintf("\tnonce: %d\n", b.Nonce) +
 		fmt.Sprintf("\tfrom: %s\n", b.From) +
 		fmt.Sprintf("\tdata: %s\n", string(b.Data)) +
+		fmt.Sprintf("\ttransactions: %d\n", len(b.Transactions)) +
 		fmt.Sprintf("\tdifficulty: %d\n", b.Difficulty) +
 		fmt.Sprintf("\tsalt: %d\n", b.Salt) +
+		fmt.Sprintf("\ttimestamp: %d\n", b.Timestamp) +
 		fmt.Sprintf("\tblockHash: %s\n", b.BlockHash)
 	return s
 }
@@ -98,6 +120,9 @@ func (b *Block) Sign() error {
 			Data:          b.Data,
 			Difficulty:    b.Difficulty,
 			Salt:          b.Salt,
+			Timestamp:     b.Timestamp,
+			TxRoot:        b.TxRoot,
+			StateRoot:     b.StateRoot,
 		},
 		BlockHash: b.BlockHash,
 	}
@@ -124,6 +149,9 @@ func (b *Block) BlockDataWithSalt(salt uint64) BlockData {
 		Data:          b.Data,
 		Difficulty:    b.Difficulty,
 		Salt:          salt,
+		Timestamp:     b.Timestamp,
+		TxRoot:        b.TxRoot,
+		StateRoot:     b.StateRoot,
 	}
 }
 
@@ -143,8 +171,29 @@ func (b *Block) Verify() (bool, error) {
 	if err != nil {
 		return false, fmt.Errorf("failed to verify signagure: %v", err)
 	}
+	if !ok {
+		return false, nil
+	}
+
+	txRoot, err := TransactionsRoot(b.Transactions)
+	if err != nil {
+		return false, fmt.Errorf("failed to get transactions root: %v", err)
+	}
+	if txRoot != b.TxRoot {
+		return false, nil
+	}
+
+	for _, tx := range b.Transactions {
+		ok, err := tx.Verify()
+		if err != nil {
+			return false, fmt.Errorf("failed to verify transaction %s: %v", tx.Hash, err)
+		}
+		if !ok {
+			return false, nil
+		}
+	}
 
-	return ok, nil
+	return true, nil
 }
 
 type BlockData struct {
@@ -154,6 +203,12 @@ type BlockData struct {
 	Data          []byte  `json:"data"`
 	Difficulty    uint64  `json:"difficulty"`
 	Salt          uint64  `json:"salt"`
+	// Timestamp is omitted when empty to keep hashes of blocks created
+	// before timestamps were introduced
+	Timestamp int64  `json:"timestamp,omitempty"`
+	TxRoot    string `json:"txRoot,omitempty"`
+	// StateRoot is root of votings state after block
+	StateRoot string `json:"stateRoot,omitempty"`
 }
 
 func (d BlockData) Hash() ([32]byte, error) {
@@ -166,7 +221,10 @@ func (d BlockData) Equal(other BlockData) bool {
 		d.From == other.From &&
 		bytes.Equal(d.Data, other.Data) &&
 		d.Difficulty == other.Difficulty &&
-		d.Salt == other.Salt
+		d.Salt == other.Salt &&
+		d.Timestamp == other.Timestamp &&
+		d.TxRoot == other.TxRoot &&
+		d.StateRoot == other.StateRoot
 }
 
 type SignBlockData struct {
@@ -5,11 +5,28 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"slices"
 )
 
 type Chain struct {
-	Blocks   []Block
+	// blocks of main chain are read from store, chains saved to single
+	// file keep them in memory
+	blocks   Store
 	filepath string
+
+	// side holds blocks of branches other than main chain by hash
+	side      map[string]Block
+	consensus Consensus
+
+	// genesis is nil for chains of default network
+	genesis      *Genesis
+	genesisBlock Block
+
+	finalityDepth uint64
+	// finalized is the highest finalized nonce seen so far
+	finalized uint64
+
+	idx *index
 }
 
 const EmptyFilepath = "::"
@@ -26,23 +43,62 @@ func NewChainFromFile(filepath string) (Chain, error) {
 			return Chain{}, fmt.Errorf("failed to read file %s: %v", filepath, err)
 		}
 		if err := json.Unmarshal(data, &blocks); err != nil {
-			return Chain{}, fmt.Errorf("failed to deserialize %v: %v", data, err)
+			return Chain{}, fmt.Errorf("%w %s: %v", ErrCorruptedChain, filepath, err)
 		}
 	}
 
+	store := NewMemoryStore(blocks)
 	return Chain{
-		Blocks:   blocks,
+		blocks:   store,
 		filepath: filepath,
+		side:     make(map[string]Block),
+		idx:      newIndex(store, DefaultGenesis()),
+	}, nil
+}
+
+// NewChainFromStore opens chain on top of store, empty store is initialized
+// with genesis block. Blocks are read from store when they are looked up,
+// state of votings is built on first lookup by reading blocks one by one.
+func NewChainFromStore(store Store) (Chain, error) {
+	if store.Length() == 0 {
+		if err := store.Append(GenesisBlock); err != nil {
+			return Chain{}, fmt.Errorf("failed to save genesis block: %w", err)
+		}
+	}
+
+	return Chain{
+		blocks:   store,
+		filepath: EmptyFilepath,
+		side:     make(map[string]Block),
+		idx:      &index{},
 	}, nil
 }
 
 func NewChain(initBlocks []Block) Chain {
+	store := NewMemoryStore(initBlocks)
 	return Chain{
-		Blocks:   initBlocks,
+		blocks:   store,
 		filepath: EmptyFilepath,
+		idx:      newIndex(store, DefaultGenesis()),
 	}
 }
 
+// view returns chain of the first length blocks of chain followed by
+// tail, which reads blocks of chain instead of copying them and can not be
+// changed.
+func (c Chain) view(length int, tail []Block) Chain {
+	c.blocks = viewStore{
+		base:   c.blocks,
+		length: length,
+		tail:   tail,
+	}
+	c.filepath = EmptyFilepath
+	c.side = nil
+	// index is built on first lookup
+	c.idx = &index{}
+	return c
+}
+
 var (
 	ErrIncorrectGenesisBlock  = errors.New("incorrect genesis block")
 	ErrIncorrectPrevBlockHash = errors.New("incorrect prevBlockHash")
@@ -52,74 +108,180 @@ var (
 )
 
 func (c Chain) Validate() (bool, error) {
-	for i, block := range c.Blocks {
+	valid, err := c.ValidPrefix()
+	if err != nil {
+		return false, err
+	}
+
+	return valid == c.Length(), nil
+}
+
+// headerLookback is number of the last blocks header of next block is
+// checked against, they cover median time span and retarget interval.
+const headerLookback = max(MedianTimeSpan, RetargetInterval+1)
+
+// ValidPrefix returns number of blocks from the start of chain which are
+// valid and error of the first invalid block.
+func (c Chain) ValidPrefix() (int, error) {
+	valid, _, err := c.validPrefix()
+	return valid, err
+}
+
+// validPrefix also returns index of valid prefix. Blocks are read one by
+// one and only the last ones are kept for checking headers.
+func (c Chain) validPrefix() (int, *index, error) {
+	// index of checked blocks is extended instead of rebuilt for every one
+	idx := newIndex(nil, c.Genesis())
+	recent := make([]Block, 0, headerLookback)
+
+	for i := range c.Length() {
+		block, err := c.blocks.Get(uint64(i))
+		if err != nil {
+			return i, idx, err
+		}
+
 		if i == 0 {
-			if !block.Equal(GenesisBlock) {
-				return false, ErrIncorrectGenesisBlock
+			if !block.Equal(c.GenesisBlock()) {
+				return i, idx, ErrIncorrectGenesisBlock
 			}
+			idx.apply(block)
+			recent = append(recent, block)
 			continue
 		}
 
-		prevBlock := c.Blocks[i-1]
-		if block.PrevBlockHash != prevBlock.BlockHash {
-			return false, ErrIncorrectPrevBlockHash
+		prevChain := c.view(i-len(recent), recent)
+		prevChain.idx = idx
+		if err := prevChain.validateNext(block); err != nil {
+			return i, idx, err
 		}
 
-		if block.Nonce-1 != prevBlock.Nonce {
-			return false, ErrIncorrectNonce
+		idx.apply(block)
+		if block.StateRoot != idx.state.Root() {
+			idx.revert(1)
+			return i, idx, ErrIncorrectStateRoot
 		}
 
-		res, err := block.Verify()
-		if err != nil {
-			return false, fmt.Errorf("failed to verify: %v", err)
-		}
-		if !res {
-			return false, ErrIncorrectSignature
+		if len(recent) == headerLookback {
+			recent = slices.Delete(recent, 0, 1)
 		}
+		recent = append(recent, block)
 	}
 
-	return true, nil
+	return c.Length(), idx, nil
 }
 
-func (c *Chain) PushBlock(b Block) (bool, error) {
-	for _, block := range c.Blocks {
-		if block.Equal(b) {
-			return false, ErrBlockIncluded
-		}
+// validateNext checks block on top of chain except its state root: link to
+// the last block, header, signature and calls.
+func (c Chain) validateNext(block Block) error {
+	prevBlock := c.GetLastBlock()
+	if block.PrevBlockHash != prevBlock.BlockHash {
+		return ErrIncorrectPrevBlockHash
+	}
+
+	if block.Nonce-1 != prevBlock.Nonce {
+		return ErrIncorrectNonce
+	}
+
+	if err := c.ValidateHeader(block); err != nil {
+		return err
 	}
 
-	newChain := NewChain(append(c.Blocks, b))
-	ok, err := newChain.Validate()
+	res, err := block.Verify()
 	if err != nil {
+		return fmt.Errorf("failed to verify: %v", err)
+	}
+	if !res {
+		return ErrIncorrectSignature
+	}
+
+	return c.ValidateBlockCalls(block)
+}
+
+// PushBlock appends block to main chain, only the block is validated on
+// top of indexed chain.
+func (c *Chain) PushBlock(b Block) (bool, error) {
+	if _, ok := c.GetBlockByHash(b.BlockHash); ok {
+		return false, ErrBlockIncluded
+	}
+
+	if err := c.validateNext(b); err != nil {
 		return false, err
 	}
-	if !ok {
-		return false, fmt.Errorf("not ok")
+	if b.StateRoot != c.NextStateRoot(b) {
+		return false, ErrIncorrectStateRoot
 	}
 
-	c.Blocks = append(c.Blocks, b)
-	c.SaveFile()
+	if err := c.replace(c.Length(), []Block{b}); err != nil {
+		return false, fmt.Errorf("failed to save chain: %w", err)
+	}
+	c.extendIndex()
+	c.updateFinalized()
 
 	return true, nil
 }
 
 func (c Chain) SaveFile() error {
 	if c.filepath != EmptyFilepath {
-		data, err := json.Marshal(c.Blocks)
-		if err != nil {
-			return fmt.Errorf("failed to serialize blocks: %v", err)
+		return c.writeFile(c.GetBlocks(0, c.Length()))
+	}
+
+	return nil
+}
+
+func (c Chain) writeFile(blocks []Block) error {
+	data, err := json.Marshal(blocks)
+	if err != nil {
+		return fmt.Errorf("failed to serialize blocks: %v", err)
+	}
+	if err := WriteFileAtomic(c.filepath, data, 0644); err != nil {
+		return fmt.Errorf("failed to save to file: %v", err)
+	}
+
+	return nil
+}
+
+// Reset drops all blocks except genesis. Chain with final blocks is never
+// reset, whether it is valid or not.
+func (c *Chain) Reset() error {
+	if c.FinalizedHeight() > 0 {
+		return ErrFinalizedBlock
+	}
+
+	if err := c.replace(0, []Block{c.GenesisBlock()}); err != nil {
+		return fmt.Errorf("failed to save chain: %w", err)
+	}
+	c.side = make(map[string]Block)
+	c.finalized = 0
+	c.reindex()
+	return nil
+}
+
+// replace puts blocks instead of blocks of chain starting from nonce from
+// and persists them. Chain saved to file is not changed when file can not
+// be written, store is changed in place, so on failure its blocks from
+// nonce from may be partly replaced.
+func (c Chain) replace(from int, blocks []Block) error {
+	if c.filepath != EmptyFilepath {
+		if err := c.writeFile(append(c.GetBlocks(0, from), blocks...)); err != nil {
+			return err
 		}
-		if err := os.WriteFile(c.filepath, data, 0644); err != nil {
-			return fmt.Errorf("failed to save to file: %v", err)
+	}
+
+	if err := c.blocks.Truncate(from); err != nil {
+		return fmt.Errorf("failed to truncate store: %v", err)
+	}
+	for _, block := range blocks {
+		if err := c.blocks.Append(block); err != nil {
+			return fmt.Errorf("failed to append block #%d: %v", block.Nonce, err)
 		}
 	}
 
 	return nil
 }
 
-func (c *Chain) Reset() {
-	c.Blocks = []Block{GenesisBlock}
-	c.SaveFile()
+// Close releases store of chain.
+func (c Chain) Close() error {
+	return c.blocks.Close()
 }
 
 func (c Chain) GetLastBlock() Block {
@@ -128,84 +290,49 @@ func (c Chain) GetLastBlock() Block {
 }
 
 func (c Chain) Length() int {
-	return len(c.Blocks)
+	if c.blocks == nil {
+		return 0
+	}
+	return c.blocks.Length()
 }
 
 func (c Chain) GetBlock(nonce int) (Block, bool) {
-	if nonce >= len(c.Blocks) {
+	if nonce < 0 || nonce >= c.Length() {
 		return Block{}, false
 	}
 
-	return c.Blocks[nonce], true
+	block, err := c.blocks.Get(uint64(nonce))
+	return block, err == nil
+}
+
+// GetBlocks returns blocks with nonces from from up to to, blocks are read
+// until the first one which can not be read.
+func (c Chain) GetBlocks(from, to int) []Block {
+	blocks := make([]Block, 0, max(to-from, 0))
+	for nonce := max(from, 0); nonce < min(to, c.Length()); nonce++ {
+		block, ok := c.GetBlock(nonce)
+		if !ok {
+			break
+		}
+		blocks = append(blocks, block)
+	}
+
+	return blocks
 }
 
 func (c Chain) String() string {
 	s := ""
-	for _, block := range c.Blocks {
+	for _, block := range c.GetBlocks(0, c.Length()) {
 		s = s + block.String() + "\n"
 	}
 	return s
 }
 
+// VotingWithBlock is voting together with block it was included in, ID
+// identifies voting and Creator is address which signed it.
 type VotingWithBlock struct {
 	Voting
 	Block
-}
-
-func (c Chain) GetVotings() []VotingWithBlock {
-	voitings := make([]VotingWithBlock, 0)
-
-	for _, block := range c.Blocks {
-		var call Call
-		if err := json.Unmarshal(block.Data, &call); err != nil {
-			continue
-		}
-		if call.Method != VotingMethod {
-			continue
-		}
-
-		var voting Voting
-		if err := json.Unmarshal(call.Data, &voting); err != nil {
-			continue
-		}
-
-		voitings = append(voitings, VotingWithBlock{
-			Block:  block,
-			Voting: voting,
-		})
-	}
-
-	return voitings
-}
-
-func (c Chain) GetVotes(blockHash string) map[Address]bool {
-	votes := make(map[Address]bool)
-
-	for _, block := range c.Blocks {
-		var call Call
-		if err := json.Unmarshal(block.Data, &call); err != nil {
-			continue
-		}
-		if call.Method != VoteMethod {
-			continue
-		}
-
-		var vote Vote
-		if err := json.Unmarshal(call.Data, &vote); err != nil {
-			continue
-		}
-
-		if vote.BlockHash != blockHash {
-			continue
-		}
-
-		_, ok := votes[block.From]
-		if ok {
-			continue
-		}
-
-		votes[block.From] = vote.Value
-	}
-
-	return votes
+	ID      string
+	Creator Address
 }
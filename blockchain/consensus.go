@@ -0,0 +1,70 @@
+package blockchain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+const (
+	ProofOfWorkConsensus      = "pow"
+	ProofOfAuthorityConsensus = "poa"
+)
+
+var ErrUnknownConsensus = errors.New("unknown consensus")
+
+// Consensus decides who may seal blocks and how, and how much each block
+// weighs in fork choice.
+type Consensus interface {
+	Name() string
+	// Prepare fills consensus fields of block which goes on top of chain
+	Prepare(chain Chain, block *Block) error
+	// Seal sets BlockHash of prepared block, it stops when ctx is canceled
+	Seal(ctx context.Context, block *Block) (MineStats, error)
+	// VerifySeal checks block on its own, without chain it belongs to
+	VerifySeal(block Block) error
+	// VerifyHeader checks consensus fields of block on top of chain
+	VerifyHeader(chain Chain, block Block) error
+	// Work returns weight of block for fork choice
+	Work(block Block) *big.Int
+}
+
+func NewConsensus(name string, validators []Address) (Consensus, error) {
+	switch name {
+	case "", ProofOfWorkConsensus:
+		return NewProofOfWork(), nil
+	case ProofOfAuthorityConsensus:
+		return NewProofOfAuthority(validators)
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnknownConsensus, name)
+	}
+}
+
+// Consensus returns consensus of chain, proof-of-work by default.
+func (c Chain) Consensus() Consensus {
+	if c.consensus == nil {
+		return NewProofOfWork()
+	}
+	return c.consensus
+}
+
+func (c Chain) WithConsensus(consensus Consensus) Chain {
+	c.consensus = consensus
+	return c
+}
+
+// Work returns cumulative work of chain.
+func (c Chain) Work() *big.Int {
+	return c.work(c.GetBlocks(0, c.Length()))
+}
+
+func (c Chain) work(blocks []Block) *big.Int {
+	consensus := c.Consensus()
+
+	work := new(big.Int)
+	for _, block := range blocks {
+		work.Add(work, consensus.Work(block))
+	}
+	return work
+}
@@ -5,14 +5,28 @@ import (
 	"crypto/elliptic"
 	"crypto/rand"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"math/big"
+	"os"
+	"strings"
+)
+
+var (
+	ErrMalformedSignature = errors.New("malformed signature")
+	ErrMalformedAddress   = errors.New("malformed address")
 )
 
 type Signature string
 
+// NewSignature encodes r and s as fixed size big-endian numbers, so
+// signature is always 64 bytes long.
 func NewSignature(r, s *big.Int) Signature {
-	signature := append(r.Bytes(), s.Bytes()...)
+	size := elliptic.P256().Params().BitSize / 8
+
+	signature := make([]byte, 2*size)
+	r.FillBytes(signature[:size])
+	s.FillBytes(signature[size:])
 	return Signature(hex.EncodeToString(signature))
 }
 
@@ -23,6 +37,9 @@ func (signature Signature) RS() (r, s *big.Int, err error) {
 	}
 
 	curveOrderByteSize := elliptic.P256().Params().BitSize / 8
+	if len(signatureBytes) != 2*curveOrderByteSize {
+		return nil, nil, fmt.Errorf("%w: expected %d bytes, got %d", ErrMalformedSignature, 2*curveOrderByteSize, len(signatureBytes))
+	}
 
 	r = new(big.Int).SetBytes(signatureBytes[:curveOrderByteSize])
 	s = new(big.Int).SetBytes(signatureBytes[curveOrderByteSize:])
@@ -33,12 +50,12 @@ func (signature Signature) RS() (r, s *big.Int, err error) {
 func (signature Signature) Verify(address Address, data []byte) (bool, error) {
 	publicKey, err := address.PublicKey()
 	if err != nil {
-		return false, fmt.Errorf("failed to get public key from address %s: %v", address, err)
+		return false, fmt.Errorf("failed to get public key from address %s: %w", address, err)
 	}
 
 	r, s, err := signature.RS()
 	if err != nil {
-		return false, fmt.Errorf("failed to get RS %s: %v", s, err)
+		return false, fmt.Errorf("failed to get RS %s: %w", signature, err)
 	}
 
 	return ecdsa.Verify(publicKey, data, r, s), nil
@@ -46,9 +63,15 @@ func (signature Signature) Verify(address Address, data []byte) (bool, error) {
 
 type Address string
 
+// NewAddressFromPublicKey encodes coordinates of key as fixed size
+// big-endian numbers, so address is always 64 bytes long.
 func NewAddressFromPublicKey(publicKey *ecdsa.PublicKey) Address {
-	publicKeyBytes := append(publicKey.X.Bytes(), publicKey.Y.Bytes()...)
-	return Address(hex.EncodeToString(publicKeyBytes[:]))
+	size := publicKey.Curve.Params().BitSize / 8
+
+	publicKeyBytes := make([]byte, 2*size)
+	publicKey.X.FillBytes(publicKeyBytes[:size])
+	publicKey.Y.FillBytes(publicKeyBytes[size:])
+	return Address(hex.EncodeToString(publicKeyBytes))
 }
 
 func (a Address) PublicKey() (*ecdsa.PublicKey, error) {
@@ -59,6 +82,9 @@ func (a Address) PublicKey() (*ecdsa.PublicKey, error) {
 
 	curve := elliptic.P256()
 	curveSize := curve.Params().BitSize / 8
+	if len(publicKeyBytes) != 2*curveSize {
+		return nil, fmt.Errorf("%w: expected %d bytes, got %d", ErrMalformedAddress, 2*curveSize, len(publicKeyBytes))
+	}
 
 	x := new(big.Int).SetBytes(publicKeyBytes[:curveSize])
 	y := new(big.Int).SetBytes(publicKeyBytes[curveSize:])
@@ -88,6 +114,28 @@ func NewWalletFromString(wallet string) Wallet {
 	return Wallet(wallet)
 }
 
+func NewWalletFromFile(filepath string) (Wallet, error) {
+	data, err := os.ReadFile(filepath)
+	if err != nil {
+		return "", fmt.Errorf("failed to read file %s: %v", filepath, err)
+	}
+
+	wallet := Wallet(strings.TrimSpace(string(data)))
+	if _, err := wallet.PrivateKey(); err != nil {
+		return "", fmt.Errorf("failed to parse wallet from %s: %v", filepath, err)
+	}
+
+	return wallet, nil
+}
+
+func (w Wallet) SaveFile(filepath string) error {
+	if err := WriteFileAtomic(filepath, []byte(string(w)+"\n"), 0600); err != nil {
+		return fmt.Errorf("failed to save wallet to %s: %v", filepath, err)
+	}
+
+	return nil
+}
+
 func (w Wallet) PrivateKey() (*ecdsa.PrivateKey, error) {
 	dBytes, err := hex.DecodeString(string(w))
 	if err != nil {
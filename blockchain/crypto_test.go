@@ -0,0 +1,92 @@
+package blockchain
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestSignatureVerifyRejectsMalformed(t *testing.T) {
+	wallet := NewRandomWallet()
+	address, err := wallet.Address()
+	if err != nil {
+		t.Fatalf("failed to get address: %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		address   Address
+		signature Signature
+		expect    error
+	}{
+		{
+			name:      "short signature",
+			address:   address,
+			signature: "00",
+			expect:    ErrMalformedSignature,
+		},
+		{
+			name:      "empty signature",
+			address:   address,
+			signature: "",
+			expect:    ErrMalformedSignature,
+		},
+		{
+			name:      "long signature",
+			address:   address,
+			signature: Signature(strings.Repeat("00", 65)),
+			expect:    ErrMalformedSignature,
+		},
+		{
+			name:      "short address",
+			address:   "00",
+			signature: Signature(strings.Repeat("00", 64)),
+			expect:    ErrMalformedAddress,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ok, err := test.signature.Verify(test.address, []byte("data"))
+			if ok || !errors.Is(err, test.expect) {
+				t.Fatalf("expected %v, got %v (ok %v)", test.expect, err, ok)
+			}
+		})
+	}
+}
+
+func TestSignaturesHaveFixedSize(t *testing.T) {
+	for range 2000 {
+		wallet := NewRandomWallet()
+		address, err := wallet.Address()
+		if err != nil {
+			t.Fatalf("failed to get address: %v", err)
+		}
+		if len(address) != 128 {
+			t.Fatalf("expected address of 64 bytes, got %s", address)
+		}
+
+		tx, err := NewTransaction(wallet, GenesisBlock.BlockHash, 1, Call{Method: VotingMethod})
+		if err != nil {
+			t.Fatalf("failed to create transaction: %v", err)
+		}
+		if len(tx.Signature) != 128 {
+			t.Fatalf("expected signature of 64 bytes, got %s", tx.Signature)
+		}
+		if ok, err := tx.Verify(); !ok || err != nil {
+			t.Fatalf("transaction %s does not verify: %v", tx.Hash, err)
+		}
+	}
+}
+
+func TestTransactionVerifyRejectsShortSignature(t *testing.T) {
+	tx, err := NewTransaction(NewRandomWallet(), GenesisBlock.BlockHash, 1, Call{Method: VotingMethod})
+	if err != nil {
+		t.Fatalf("failed to create transaction: %v", err)
+	}
+	tx.Signature = tx.Signature[:len(tx.Signature)-2]
+
+	if ok, err := tx.Verify(); ok || !errors.Is(err, ErrMalformedSignature) {
+		t.Fatalf("expected %v, got %v (ok %v)", ErrMalformedSignature, err, ok)
+	}
+}
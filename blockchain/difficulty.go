@@ -0,0 +1,103 @@
+package blockchain
+
+import (
+	"encoding/hex"
+	"errors"
+	"math"
+	"slices"
+	"time"
+)
+
+// Difficulty of block is number of leading zero bits its hash must have.
+const (
+	MinDifficulty = 8
+	MaxDifficulty = 255
+
+	// RetargetInterval is number of blocks between difficulty adjustments
+	RetargetInterval = 10
+	// TargetBlockTime is desired time between blocks in seconds
+	TargetBlockTime = 10
+	// MaxRetargetStep limits change of difficulty per adjustment in bits
+	MaxRetargetStep = 2
+
+	// MedianTimeSpan is number of previous blocks whose median timestamp
+	// block must not be earlier than
+	MedianTimeSpan = 11
+	// MaxFutureBlockTime is how far in seconds block timestamp may be
+	// ahead of local clock
+	MaxFutureBlockTime = 2 * 60
+)
+
+var (
+	ErrIncorrectDifficulty = errors.New("incorrect difficulty")
+	ErrInvalidTimestamp    = errors.New("invalid timestamp")
+	ErrInsufficientWork    = errors.New("block hash does not meet difficulty")
+)
+
+// NextDifficulty returns difficulty required for block on top of chain.
+// Every RetargetInterval blocks difficulty moves toward TargetBlockTime by
+// the time last RetargetInterval blocks took, at most MaxRetargetStep bits
+// at once.
+func (c Chain) NextDifficulty() uint64 {
+	lastBlock := c.GetLastBlock()
+	nonce := lastBlock.Nonce + 1
+
+	// window must not include genesis block which has no timestamp
+	if nonce%RetargetInterval != 0 || nonce < 2*RetargetInterval {
+		return lastBlock.Difficulty
+	}
+
+	first, _ := c.GetBlock(int(nonce - 1 - RetargetInterval))
+	actual := max(lastBlock.Timestamp-first.Timestamp, 1)
+	expected := int64(RetargetInterval * TargetBlockTime)
+
+	step := int64(math.Round(math.Log2(float64(expected) / float64(actual))))
+	step = min(max(step, -MaxRetargetStep), MaxRetargetStep)
+
+	difficulty := int64(lastBlock.Difficulty) + step
+	return uint64(min(max(difficulty, MinDifficulty), MaxDifficulty))
+}
+
+// MedianTime returns median timestamp of last MedianTimeSpan blocks.
+func (c Chain) MedianTime() int64 {
+	blocks := c.GetBlocks(c.Length()-MedianTimeSpan, c.Length())
+	if len(blocks) == 0 {
+		return 0
+	}
+
+	timestamps := make([]int64, 0, len(blocks))
+	for _, block := range blocks {
+		timestamps = append(timestamps, block.Timestamp)
+	}
+	slices.Sort(timestamps)
+
+	return timestamps[len(timestamps)/2]
+}
+
+// HasEnoughWork reports whether block hash has at least as many leading
+// zero bits as block difficulty.
+func (b Block) HasEnoughWork() bool {
+	hash, err := hex.DecodeString(b.BlockHash)
+	if err != nil || len(hash) != 32 {
+		return false
+	}
+
+	return GetDifficulty([32]byte(hash)) >= b.Difficulty
+}
+
+// ValidateHeader checks consensus fields and timestamp of block on top of
+// chain.
+func (c Chain) ValidateHeader(block Block) error {
+	if err := c.Consensus().VerifyHeader(c, block); err != nil {
+		return err
+	}
+
+	if block.Timestamp < c.MedianTime() {
+		return ErrInvalidTimestamp
+	}
+	if block.Timestamp > time.Now().Unix()+MaxFutureBlockTime {
+		return ErrInvalidTimestamp
+	}
+
+	return nil
+}
@@ -0,0 +1,66 @@
+package blockchain
+
+import "errors"
+
+var (
+	ErrEmptyElectorate     = errors.New("electorate is empty")
+	ErrAmbiguousElectorate = errors.New("electorate must have exactly one of addresses, merkle root, registry or genesis")
+	ErrIneligibleVoter     = errors.New("voter is not eligible")
+)
+
+// Electorate declares who may vote in voting: explicit list of addresses,
+// merkle root of eligible addresses, registry voting, in which case
+// everyone whose ballot is counted in registry is eligible, or name of
+// electorate declared in genesis.
+type Electorate struct {
+	Addresses  []Address `json:"addresses,omitempty"`
+	MerkleRoot string    `json:"merkleRoot,omitempty"`
+	Registry   string    `json:"registry,omitempty"`
+	Genesis    string    `json:"genesis,omitempty"`
+}
+
+func NewAddressElectorate(addresses []Address) *Electorate {
+	return &Electorate{Addresses: addresses}
+}
+
+func NewMerkleElectorate(root string) *Electorate {
+	return &Electorate{MerkleRoot: root}
+}
+
+func NewRegistryElectorate(registry string) *Electorate {
+	return &Electorate{Registry: registry}
+}
+
+func NewGenesisElectorate(name string) *Electorate {
+	return &Electorate{Genesis: name}
+}
+
+func (e Electorate) Validate() error {
+	kinds := 0
+	if len(e.Addresses) > 0 {
+		kinds++
+	}
+	if e.MerkleRoot != "" {
+		kinds++
+	}
+	if e.Registry != "" {
+		kinds++
+	}
+	if e.Genesis != "" {
+		kinds++
+	}
+
+	if kinds == 0 {
+		return ErrEmptyElectorate
+	}
+	if kinds > 1 {
+		return ErrAmbiguousElectorate
+	}
+
+	return nil
+}
+
+// IsEligible reports whether address may cast vote in voting.
+func (c Chain) IsEligible(voting VotingState, address Address, vote Vote) bool {
+	return c.index().state.IsEligible(voting.Voting, voting.Nonce, address, vote)
+}
@@ -0,0 +1,77 @@
+package blockchain
+
+import "errors"
+
+// DefaultFinalityDepth is number of blocks on top of block after which it
+// is final and can not be removed by reorg or reset.
+const DefaultFinalityDepth = 10
+
+var ErrFinalizedBlock = errors.New("block is finalized")
+
+func (c Chain) WithFinalityDepth(depth uint64) Chain {
+	c.finalityDepth = depth
+	return c
+}
+
+func (c Chain) FinalityDepth() uint64 {
+	if c.finalityDepth == 0 {
+		return DefaultFinalityDepth
+	}
+	return c.finalityDepth
+}
+
+// FinalizedHeight returns nonce of the last final block. Once block is
+// final it stays final even if heavier branch would be shorter.
+func (c Chain) FinalizedHeight() uint64 {
+	lastNonce := c.GetLastBlock().Nonce
+	depth := c.FinalityDepth()
+	if lastNonce < depth {
+		return c.finalized
+	}
+
+	return max(c.finalized, lastNonce-depth)
+}
+
+func (c Chain) IsFinal(block Block) bool {
+	return block.Nonce <= c.FinalizedHeight()
+}
+
+func (c *Chain) updateFinalized() {
+	c.finalized = c.FinalizedHeight()
+}
+
+// GetVotingEnd returns first block after which no more votes are counted
+// for voting, that is block closing voting or first block past its window.
+func (c Chain) GetVotingEnd(id string) (Block, bool) {
+	voting, ok := c.GetVoting(id)
+	if !ok {
+		return Block{}, false
+	}
+
+	closeBlock, closed := c.GetVotingClose(id)
+	for nonce := int(voting.Nonce); nonce < c.Length(); nonce++ {
+		block, ok := c.GetBlock(nonce)
+		if !ok {
+			break
+		}
+		if closed && block.Nonce == closeBlock.Nonce {
+			return closeBlock, true
+		}
+		if voting.Ended(block) {
+			return block, true
+		}
+	}
+
+	return Block{}, false
+}
+
+// IsVotingFinal reports whether voting ended in final block, so its result
+// can not change anymore.
+func (c Chain) IsVotingFinal(id string) bool {
+	block, ok := c.GetVotingEnd(id)
+	if !ok {
+		return false
+	}
+
+	return c.IsFinal(block)
+}
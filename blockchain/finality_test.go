@@ -0,0 +1,141 @@
+package blockchain
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestFinalizedHeight(t *testing.T) {
+	tests := []struct {
+		name   string
+		depth  uint64
+		blocks int
+		height uint64
+	}{
+		{name: "chain shorter than depth", depth: 5, blocks: 3, height: 0},
+		{name: "chain longer than depth", depth: 2, blocks: 3, height: 1},
+		{name: "default depth", depth: 0, blocks: DefaultFinalityDepth + 1, height: 1},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			chain := NewChain([]Block{GenesisBlock}).WithFinalityDepth(test.depth)
+			for range test.blocks {
+				pushTestBlock(t, &chain, nil)
+			}
+
+			if height := chain.FinalizedHeight(); height != test.height {
+				t.Fatalf("expected finalized height %d, got %d", test.height, height)
+			}
+			for nonce := range chain.Length() {
+				block, _ := chain.GetBlock(nonce)
+				if chain.IsFinal(block) != (block.Nonce <= test.height) {
+					t.Fatalf("block #%d is final %v", nonce, chain.IsFinal(block))
+				}
+			}
+		})
+	}
+}
+
+func TestResetRefusesFinalBlocks(t *testing.T) {
+	tests := []struct {
+		name  string
+		depth uint64
+		err   error
+	}{
+		{name: "chain without final blocks", depth: 5},
+		{name: "chain with final blocks", depth: 1, err: ErrFinalizedBlock},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			chain := NewChain([]Block{GenesisBlock}).WithFinalityDepth(test.depth)
+			for range 3 {
+				pushTestBlock(t, &chain, nil)
+			}
+			tip := chain.GetLastBlock()
+
+			err := chain.Reset()
+			if !errors.Is(err, test.err) {
+				t.Fatalf("expected %v, got %v", test.err, err)
+			}
+
+			if err != nil && chain.GetLastBlock().BlockHash != tip.BlockHash {
+				t.Fatalf("refused reset changed chain")
+			}
+			if err == nil && chain.Length() != 1 {
+				t.Fatalf("expected only genesis block after reset, got %d blocks", chain.Length())
+			}
+		})
+	}
+}
+
+func TestIsVotingFinal(t *testing.T) {
+	tests := []struct {
+		name   string
+		voting Voting
+		// close closes voting in the first block after voting
+		close bool
+		// blocks is number of blocks pushed after block holding voting
+		blocks int
+		final  bool
+	}{
+		{
+			name:   "open voting",
+			voting: NewVoting("voting"),
+			blocks: 3,
+		},
+		{
+			name:   "window ended in block which is not final",
+			voting: NewVoting("voting").WithHeightWindow(0, 2),
+			blocks: 1,
+		},
+		{
+			name:   "window ended in final block",
+			voting: NewVoting("voting").WithHeightWindow(0, 2),
+			blocks: 2,
+			final:  true,
+		},
+		{
+			name:   "closed in block which is not final",
+			voting: NewVoting("voting"),
+			close:  true,
+			blocks: 1,
+		},
+		{
+			name:   "closed in final block",
+			voting: NewVoting("voting"),
+			close:  true,
+			blocks: 2,
+			final:  true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			creator := NewRandomWallet()
+			chain := NewChain([]Block{GenesisBlock}).WithFinalityDepth(1)
+
+			voting := newTestTransaction(t, creator, 1, VotingMethod, test.voting)
+			pushTestBlock(t, &chain, []Transaction{voting})
+			for i := range test.blocks {
+				txs := []Transaction{}
+				if test.close && i == 0 {
+					txs = append(txs, newTestTransaction(t, creator, 2, CloseMethod, NewClose(voting.Hash)))
+				}
+				pushTestBlock(t, &chain, txs)
+			}
+
+			if final := chain.IsVotingFinal(voting.Hash); final != test.final {
+				t.Fatalf("expected voting final %v, got %v", test.final, final)
+			}
+			tally, err := chain.GetTally(voting.Hash)
+			if err != nil {
+				t.Fatalf("failed to get tally: %v", err)
+			}
+			if tally.Finalized != test.final {
+				t.Fatalf("expected tally finalized %v, got %v", test.final, tally.Finalized)
+			}
+		})
+	}
+}
@@ -0,0 +1,257 @@
+package blockchain
+
+import (
+	"errors"
+	"fmt"
+)
+
+const (
+	// MaxSideDepth is how deep below the tip side branches are kept.
+	MaxSideDepth = 100
+	// MaxSideBlocksPerHeight is number of side blocks kept with the same
+	// nonce, MaxSideBlocks is number of all side blocks, so peers can not
+	// fill memory with valid siblings.
+	MaxSideBlocksPerHeight = 8
+	MaxSideBlocks          = 1024
+)
+
+var (
+	ErrUnknownParent     = errors.New("unknown parent block")
+	ErrStaleBlock        = errors.New("block is too deep below tip")
+	ErrTooManySideBlocks = errors.New("too many side blocks")
+)
+
+type AddStatus string
+
+const (
+	// BlockExtended means block was appended to the tip of main chain
+	BlockExtended AddStatus = "extended"
+	// BlockSide means block was stored in side branch with less work
+	BlockSide AddStatus = "side"
+	// BlockReorged means main chain was switched to branch ending with
+	// block
+	BlockReorged AddStatus = "reorged"
+)
+
+// AddResult describes how block changed chain, Removed holds blocks which
+// left main chain on reorg starting from the lowest one.
+type AddResult struct {
+	Status  AddStatus
+	Removed []Block
+}
+
+// HasBlock reports whether block is known either in main chain or in
+// side branches.
+func (c Chain) HasBlock(blockHash string) bool {
+	if _, ok := c.side[blockHash]; ok {
+		return true
+	}
+	_, ok := c.GetBlockByHash(blockHash)
+	return ok
+}
+
+// AddBlock puts block to block tree. Block extending the tip is pushed to
+// main chain, block on another branch is kept aside and when its branch
+// has more cumulative work than main chain, chain is reorganized to it.
+func (c *Chain) AddBlock(b Block) (AddResult, error) {
+	if c.HasBlock(b.BlockHash) {
+		return AddResult{}, ErrBlockIncluded
+	}
+
+	lastBlock := c.GetLastBlock()
+	if b.PrevBlockHash == lastBlock.BlockHash {
+		if _, err := c.PushBlock(b); err != nil {
+			return AddResult{}, err
+		}
+		c.pruneSide()
+		return AddResult{Status: BlockExtended}, nil
+	}
+
+	parent, ok := c.getTreeBlock(b.PrevBlockHash)
+	if !ok {
+		return AddResult{}, fmt.Errorf("%w: %s", ErrUnknownParent, b.PrevBlockHash)
+	}
+	if b.Nonce != parent.Nonce+1 {
+		return AddResult{}, ErrIncorrectNonce
+	}
+	if b.Nonce+MaxSideDepth <= lastBlock.Nonce {
+		return AddResult{}, ErrStaleBlock
+	}
+	// reorg to branch of block would remove final block of main chain
+	if parent.Nonce < c.FinalizedHeight() {
+		return AddResult{}, ErrFinalizedBlock
+	}
+
+	// header is checked against its branch only on reorg, but side blocks
+	// must carry valid seal to be stored at all
+	if err := c.Consensus().VerifySeal(b); err != nil {
+		return AddResult{}, err
+	}
+
+	ok, err := b.Verify()
+	if err != nil {
+		return AddResult{}, fmt.Errorf("failed to verify: %v", err)
+	}
+	if !ok {
+		return AddResult{}, ErrIncorrectSignature
+	}
+
+	if err := c.addSide(b); err != nil {
+		return AddResult{}, err
+	}
+
+	branch := c.branch(b)
+	forkNonce := branch[0].Nonce - 1
+	if c.work(branch).Cmp(c.work(c.GetBlocks(int(forkNonce)+1, c.Length()))) <= 0 {
+		return AddResult{Status: BlockSide}, nil
+	}
+
+	removed, err := c.reorg(branch)
+	if err != nil {
+		delete(c.side, b.BlockHash)
+		return AddResult{}, fmt.Errorf("failed to reorganize to %s: %w", b.BlockHash, err)
+	}
+
+	return AddResult{
+		Status:  BlockReorged,
+		Removed: removed,
+	}, nil
+}
+
+// checkSideLimits fails when block can not be stored aside without
+// exceeding limits of side blocks.
+func (c Chain) checkSideLimits(b Block) error {
+	if len(c.side) >= MaxSideBlocks {
+		return fmt.Errorf("%w: %d stored", ErrTooManySideBlocks, len(c.side))
+	}
+
+	siblings := 0
+	for _, block := range c.side {
+		if block.Nonce == b.Nonce {
+			siblings++
+		}
+	}
+	if siblings >= MaxSideBlocksPerHeight {
+		return fmt.Errorf("%w: %d at height %d", ErrTooManySideBlocks, siblings, b.Nonce)
+	}
+
+	return nil
+}
+
+// addSide stores block aside unless it exceeds limits of side blocks.
+func (c *Chain) addSide(b Block) error {
+	if err := c.checkSideLimits(b); err != nil {
+		return err
+	}
+
+	if c.side == nil {
+		c.side = make(map[string]Block)
+	}
+	c.side[b.BlockHash] = b
+	return nil
+}
+
+func (c Chain) getTreeBlock(blockHash string) (Block, bool) {
+	if block, ok := c.side[blockHash]; ok {
+		return block, true
+	}
+	return c.GetBlockByHash(blockHash)
+}
+
+// branch returns side blocks from fork point with main chain up to tip.
+func (c Chain) branch(tip Block) []Block {
+	branch := []Block{tip}
+	for {
+		parent, ok := c.side[branch[0].PrevBlockHash]
+		if !ok {
+			return branch
+		}
+		branch = append([]Block{parent}, branch...)
+	}
+}
+
+// reorg replaces main chain after fork point with branch. Index is rewound
+// to fork point and blocks of branch are validated on it as they were
+// pushed one by one, so blocks below fork point are not replayed.
+func (c *Chain) reorg(branch []Block) ([]Block, error) {
+	fork := int(branch[0].Nonce)
+	if uint64(fork-1) < c.FinalizedHeight() {
+		return nil, ErrFinalizedBlock
+	}
+
+	removed := c.GetBlocks(fork, c.Length())
+	if len(removed) != c.Length()-fork {
+		return nil, fmt.Errorf("failed to read block #%d", fork+len(removed))
+	}
+
+	idx := c.index()
+	if !idx.revert(len(removed)) {
+		// fork is deeper than journal of index, so index of fork point is
+		// built anew and index of chain is kept
+		idx = newIndex(viewStore{base: c.blocks, length: fork}, c.Genesis())
+	}
+
+	applied, err := c.validateBranch(idx, fork, branch)
+	if err == nil {
+		if err = c.replace(fork, branch); err != nil {
+			// bring persisted blocks back in line with main chain
+			if restoreErr := c.replace(fork, removed); restoreErr != nil {
+				err = fmt.Errorf("%w; failed to restore saved chain: %v", err, restoreErr)
+			}
+			err = fmt.Errorf("failed to save chain: %w", err)
+		}
+	}
+	if err != nil {
+		if idx == c.idx {
+			idx.revert(applied)
+			for _, block := range removed {
+				idx.apply(block)
+			}
+		}
+		return nil, err
+	}
+	c.idx = idx
+
+	for _, block := range branch {
+		delete(c.side, block.BlockHash)
+	}
+	// removed blocks which do not fit limits of side blocks are dropped,
+	// lower ones are kept as higher ones can not be used without them
+	for _, block := range removed {
+		c.addSide(block)
+	}
+
+	c.updateFinalized()
+	c.pruneSide()
+
+	return removed, nil
+}
+
+// validateBranch validates branch on top of the first fork blocks of chain
+// applying its blocks to idx of fork point, it returns number of applied
+// blocks.
+func (c Chain) validateBranch(idx *index, fork int, branch []Block) (int, error) {
+	for i, block := range branch {
+		prevChain := c.view(fork, branch[:i])
+		prevChain.idx = idx
+		if err := prevChain.validateNext(block); err != nil {
+			return i, fmt.Errorf("invalid block #%d: %w", block.Nonce, err)
+		}
+
+		idx.apply(block)
+		if block.StateRoot != idx.state.Root() {
+			return i + 1, fmt.Errorf("invalid block #%d: %w", block.Nonce, ErrIncorrectStateRoot)
+		}
+	}
+
+	return len(branch), nil
+}
+
+func (c *Chain) pruneSide() {
+	lastNonce := c.GetLastBlock().Nonce
+	for hash, block := range c.side {
+		if block.Nonce+MaxSideDepth <= lastNonce {
+			delete(c.side, hash)
+		}
+	}
+}
@@ -0,0 +1,189 @@
+package blockchain
+
+import (
+	"errors"
+	"testing"
+)
+
+// mineOn mines and signs block on top of the first fork blocks of chain
+// followed by tail.
+func mineOn(t *testing.T, chain Chain, fork int, tail []Block) Block {
+	t.Helper()
+
+	block := newTestBlock(t, chain.view(fork, tail), NewRandomWallet())
+	mineTestBlock(t, &block)
+	signTestBlock(t, &block)
+
+	return block
+}
+
+// mineBranch mines n blocks on top of the first fork blocks of chain.
+func mineBranch(t *testing.T, chain Chain, fork int, n int) []Block {
+	t.Helper()
+
+	blocks := make([]Block, 0, n)
+	for range n {
+		blocks = append(blocks, mineOn(t, chain, fork, blocks))
+	}
+
+	return blocks
+}
+
+func TestAddBlockForkChoice(t *testing.T) {
+	tests := []struct {
+		name string
+		// main is number of blocks mined on top of genesis
+		main  int
+		depth uint64
+		// branch returns blocks added in order, all but the last must be
+		// accepted
+		branch  func(t *testing.T, chain Chain) []Block
+		status  AddStatus
+		err     error
+		removed int
+	}{
+		{
+			name: "lighter branch is kept aside",
+			main: 2,
+			branch: func(t *testing.T, chain Chain) []Block {
+				return mineBranch(t, chain, 2, 1)
+			},
+			status: BlockSide,
+		},
+		{
+			name: "branch with equal work is kept aside",
+			main: 2,
+			branch: func(t *testing.T, chain Chain) []Block {
+				return mineBranch(t, chain, 1, 2)
+			},
+			status: BlockSide,
+		},
+		{
+			name: "heavier branch reorganizes chain",
+			main: 2,
+			branch: func(t *testing.T, chain Chain) []Block {
+				return mineBranch(t, chain, 1, 3)
+			},
+			status:  BlockReorged,
+			removed: 2,
+		},
+		{
+			name: "block extending tip",
+			main: 1,
+			branch: func(t *testing.T, chain Chain) []Block {
+				return mineBranch(t, chain, 2, 1)
+			},
+			status: BlockExtended,
+		},
+		{
+			name: "unknown parent",
+			main: 1,
+			branch: func(t *testing.T, chain Chain) []Block {
+				return mineBranch(t, chain, 1, 2)[1:]
+			},
+			err: ErrUnknownParent,
+		},
+		{
+			name: "too many siblings",
+			main: 1,
+			branch: func(t *testing.T, chain Chain) []Block {
+				siblings := make([]Block, 0)
+				for range MaxSideBlocksPerHeight + 1 {
+					siblings = append(siblings, mineBranch(t, chain, 1, 1)...)
+				}
+				return siblings
+			},
+			err: ErrTooManySideBlocks,
+		},
+		{
+			name:  "branch removing final block",
+			main:  3,
+			depth: 1,
+			branch: func(t *testing.T, chain Chain) []Block {
+				return mineBranch(t, chain, 2, 1)
+			},
+			err: ErrFinalizedBlock,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			chain := NewChain([]Block{GenesisBlock}).WithFinalityDepth(test.depth)
+			for _, block := range mineBranch(t, chain, 1, test.main) {
+				if _, err := chain.PushBlock(block); err != nil {
+					t.Fatalf("failed to push block: %v", err)
+				}
+			}
+			tip := chain.GetLastBlock()
+
+			blocks := test.branch(t, chain)
+			last := blocks[len(blocks)-1]
+			for _, block := range blocks[:len(blocks)-1] {
+				if _, err := chain.AddBlock(block); err != nil {
+					t.Fatalf("failed to add block #%d: %v", block.Nonce, err)
+				}
+			}
+
+			res, err := chain.AddBlock(last)
+			if !errors.Is(err, test.err) {
+				t.Fatalf("expected %v, got %v", test.err, err)
+			}
+			if res.Status != test.status || len(res.Removed) != test.removed {
+				t.Fatalf("expected %s with %d removed, got %s with %d", test.status, test.removed, res.Status, len(res.Removed))
+			}
+
+			switch {
+			case test.status == BlockSide:
+				if chain.GetLastBlock().BlockHash != tip.BlockHash || !chain.HasBlock(last.BlockHash) {
+					t.Fatalf("side block is not kept aside")
+				}
+			case test.status != "":
+				if chain.GetLastBlock().BlockHash != last.BlockHash {
+					t.Fatalf("tip is not block %s", last.BlockHash)
+				}
+			case test.err != nil:
+				if chain.GetLastBlock().BlockHash != tip.BlockHash || chain.HasBlock(last.BlockHash) {
+					t.Fatalf("rejected block changed chain")
+				}
+			}
+
+			for _, block := range res.Removed {
+				if !chain.HasBlock(block.BlockHash) {
+					t.Fatalf("removed block #%d is not kept aside", block.Nonce)
+				}
+			}
+			if ok, err := chain.Validate(); !ok || err != nil {
+				t.Fatalf("chain is not valid: %v", err)
+			}
+		})
+	}
+}
+
+func TestReorgRevertsState(t *testing.T) {
+	creator := NewRandomWallet()
+	chain := NewChain([]Block{GenesisBlock})
+
+	voting := newTestTransaction(t, creator, 1, VotingMethod, NewVoting("voting"))
+	pushTestBlock(t, &chain, []Transaction{voting})
+	root := chain.StateRoot()
+
+	// branch without voting replaces block holding it
+	for _, block := range mineBranch(t, chain, 1, 2) {
+		if _, err := chain.AddBlock(block); err != nil {
+			t.Fatalf("failed to add block: %v", err)
+		}
+	}
+
+	if _, ok := chain.GetVotingState(voting.Hash); ok {
+		t.Fatalf("voting of removed block is kept")
+	}
+	if chain.StateRoot() == root {
+		t.Fatalf("state root is not reverted")
+	}
+	if chain.GetSequence(voting.From) != 0 {
+		t.Fatalf("sequence of removed transaction is kept")
+	}
+	if chain.StateRoot() != NewChain(chain.GetBlocks(0, chain.Length())).StateRoot() {
+		t.Fatalf("state after reorg differs from replayed state")
+	}
+}
@@ -0,0 +1,175 @@
+package blockchain
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+)
+
+const DefaultChainID = "go-vote"
+
+var (
+	ErrEmptyChainID      = errors.New("chain id is empty")
+	ErrInvalidGenesis    = errors.New("invalid genesis")
+	ErrNotAdmin          = errors.New("only admins may create votings")
+	ErrUnknownElectorate = errors.New("unknown genesis electorate")
+)
+
+// Genesis describes network, every node of network must use the same
+// genesis, which is checked by comparing hashes of genesis blocks.
+type Genesis struct {
+	ChainID    string `json:"chainId"`
+	Timestamp  int64  `json:"timestamp"`
+	Difficulty uint64 `json:"difficulty"`
+	Consensus  string `json:"consensus"`
+	// Validators seal blocks of proof-of-authority network
+	Validators []Address `json:"validators,omitempty"`
+	// Admins may create votings, anyone may when there are no admins
+	Admins []Address `json:"admins,omitempty"`
+	// Electorates are named electorates which votings may refer to
+	Electorates   map[string]Electorate `json:"electorates,omitempty"`
+	FinalityDepth uint64                `json:"finalityDepth,omitempty"`
+}
+
+// DefaultGenesis describes network of chains created without genesis,
+// its block is GenesisBlock.
+func DefaultGenesis() Genesis {
+	return Genesis{
+		ChainID:    DefaultChainID,
+		Difficulty: DefaultDifficulty,
+		Consensus:  ProofOfWorkConsensus,
+	}
+}
+
+func NewGenesisFromFile(filepath string) (Genesis, error) {
+	data, err := os.ReadFile(filepath)
+	if err != nil {
+		return Genesis{}, fmt.Errorf("failed to read file %s: %v", filepath, err)
+	}
+
+	var genesis Genesis
+	if err := json.Unmarshal(data, &genesis); err != nil {
+		return Genesis{}, fmt.Errorf("failed to deserialize genesis %s: %v", filepath, err)
+	}
+
+	if err := genesis.Validate(); err != nil {
+		return Genesis{}, fmt.Errorf("%w %s: %v", ErrInvalidGenesis, filepath, err)
+	}
+
+	return genesis, nil
+}
+
+func (g Genesis) Validate() error {
+	if strings.TrimSpace(g.ChainID) == "" {
+		return ErrEmptyChainID
+	}
+
+	if _, err := g.NewConsensus(); err != nil {
+		return err
+	}
+	if g.Consensus != ProofOfAuthorityConsensus && g.Difficulty < MinDifficulty {
+		return fmt.Errorf("%w: difficulty must be at least %d", ErrIncorrectDifficulty, MinDifficulty)
+	}
+
+	for _, admin := range g.Admins {
+		if _, err := admin.PublicKey(); err != nil {
+			return fmt.Errorf("invalid admin %s: %v", admin, err)
+		}
+	}
+
+	for name, electorate := range g.Electorates {
+		if err := electorate.Validate(); err != nil {
+			return fmt.Errorf("invalid electorate %s: %v", name, err)
+		}
+		// genesis electorates can not depend on chain
+		if electorate.Registry != "" || electorate.Genesis != "" {
+			return fmt.Errorf("invalid electorate %s: only addresses or merkle root are allowed", name)
+		}
+	}
+
+	return nil
+}
+
+func (g Genesis) NewConsensus() (Consensus, error) {
+	return NewConsensus(g.Consensus, g.Validators)
+}
+
+// Block returns genesis block, its hash commits to the whole genesis.
+func (g Genesis) Block() (Block, error) {
+	data := BlockData{
+		PrevBlockHash: ZeroHash,
+		Difficulty:    g.Difficulty,
+		Timestamp:     g.Timestamp,
+	}
+
+	hash, err := Hash(struct {
+		BlockData
+		Genesis Genesis `json:"genesis"`
+	}{data, g})
+	if err != nil {
+		return Block{}, fmt.Errorf("failed to hash genesis: %v", err)
+	}
+
+	return Block{
+		SignBlockData: SignBlockData{
+			BlockData: data,
+			BlockHash: hex.EncodeToString(hash[:]),
+		},
+	}, nil
+}
+
+// IsAdmin reports whether address may create votings.
+func (g Genesis) IsAdmin(address Address) bool {
+	return len(g.Admins) == 0 || slices.Contains(g.Admins, address)
+}
+
+// Genesis returns genesis of chain network.
+func (c Chain) Genesis() Genesis {
+	if c.genesis == nil {
+		return DefaultGenesis()
+	}
+	return *c.genesis
+}
+
+func (c Chain) GenesisBlock() Block {
+	if c.genesis == nil {
+		return GenesisBlock
+	}
+	return c.genesisBlock
+}
+
+// WithGenesis makes chain belong to network of genesis, which must be
+// valid. It also sets consensus and finality depth of genesis, and chain
+// which was not started yet begins from genesis block.
+func (c Chain) WithGenesis(genesis Genesis) (Chain, error) {
+	block, err := genesis.Block()
+	if err != nil {
+		return Chain{}, err
+	}
+	consensus, err := genesis.NewConsensus()
+	if err != nil {
+		return Chain{}, fmt.Errorf("%w: %v", ErrInvalidGenesis, err)
+	}
+
+	first, _ := c.GetBlock(0)
+	if c.Length() == 1 && first.Equal(c.GenesisBlock()) && !block.Equal(first) {
+		if err := c.replace(0, []Block{block}); err != nil {
+			return Chain{}, fmt.Errorf("failed to save genesis block: %w", err)
+		}
+	}
+
+	c.genesis = &genesis
+	c.genesisBlock = block
+	c.consensus = consensus
+	if genesis.FinalityDepth != 0 {
+		c.finalityDepth = genesis.FinalityDepth
+	}
+	// state depends on electorates of genesis
+	c.reindex()
+
+	return c, nil
+}
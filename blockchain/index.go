@@ -0,0 +1,306 @@
+package blockchain
+
+import (
+	"maps"
+	"slices"
+)
+
+// maxJournal is number of the last blocks index can revert, deeper blocks
+// can not be removed by reorg as side branches are not kept below.
+const maxJournal = MaxSideDepth
+
+// index holds lookups over blocks of chain and state of votings, so
+// queries do not scan and decode every block, blocks are found by hash in
+// store. It is extended when block is pushed, reverted and extended on
+// reorg and rebuilt on reset.
+type index struct {
+	length int
+	tip    string
+
+	addresses map[Address][]uint64
+	sequences map[Address]uint64
+
+	// state holds votings, their closes and ballots
+	state *State
+	// journal holds functions reverting the last applied blocks
+	journal []func()
+
+	// tallies are cached for chain tip and finalized height
+	tallies   map[string]Tally
+	finalized uint64
+}
+
+// newIndex builds index of blocks of store, which may be nil, reading them
+// one by one. Index ends before block which can not be read, chain holding
+// it is cut by recovery.
+func newIndex(blocks Store, genesis Genesis) *index {
+	idx := &index{
+		addresses: make(map[Address][]uint64),
+		sequences: make(map[Address]uint64),
+		state:     NewState(genesis),
+		journal:   make([]func(), 0),
+		tallies:   make(map[string]Tally),
+	}
+
+	if blocks == nil {
+		return idx
+	}
+	for nonce := range blocks.Length() {
+		block, err := blocks.Get(uint64(nonce))
+		if err != nil {
+			break
+		}
+		idx.apply(block)
+	}
+
+	return idx
+}
+
+// matches reports whether index was built for blocks of chain.
+func (x *index) matches(c Chain) bool {
+	if x == nil || x.state == nil || x.length != c.Length() {
+		return false
+	}
+
+	return x.length == 0 || x.tip == c.GetLastBlock().BlockHash
+}
+
+func (x *index) apply(block Block) {
+	nonce := uint64(x.length)
+	undo := make([]func(), 0)
+	clear(x.tallies)
+
+	length, tip := x.length, x.tip
+	x.length++
+	x.tip = block.BlockHash
+	undo = append(undo, func() { x.length, x.tip = length, tip })
+
+	undo = append(undo, x.addAddress(block.From, nonce))
+	for _, tx := range block.Transactions {
+		undo = append(undo, x.addAddress(tx.From, nonce))
+	}
+
+	undo = append(undo, x.addSequences(block), x.state.Apply(block))
+
+	x.journal = append(x.journal, func() {
+		for i := len(undo) - 1; i >= 0; i-- {
+			undo[i]()
+		}
+	})
+	if len(x.journal) > maxJournal {
+		x.journal = slices.Delete(x.journal, 0, 1)
+	}
+}
+
+// applyCalls applies transactions and calls of block which is not part
+// of chain, so calls on top of it can be validated, and returns function
+// reverting them. Length and tip of index are kept.
+func (x *index) applyCalls(block Block) func() {
+	clear(x.tallies)
+	undoSequences := x.addSequences(block)
+	undoState := x.state.Apply(block)
+
+	return func() {
+		undoState()
+		undoSequences()
+		clear(x.tallies)
+	}
+}
+
+// addSequences raises sequences of senders of block transactions and
+// returns function restoring them.
+func (x *index) addSequences(block Block) func() {
+	undo := make([]func(), 0)
+	for _, tx := range block.Transactions {
+		from := tx.From
+		sequence, ok := x.sequences[from]
+		if tx.Sequence <= sequence {
+			continue
+		}
+
+		x.sequences[from] = tx.Sequence
+		undo = append(undo, func() {
+			if ok {
+				x.sequences[from] = sequence
+			} else {
+				delete(x.sequences, from)
+			}
+		})
+	}
+
+	return func() {
+		for i := len(undo) - 1; i >= 0; i-- {
+			undo[i]()
+		}
+	}
+}
+
+// revert removes the last blocks from index, it fails when blocks are
+// deeper than journal.
+func (x *index) revert(blocks int) bool {
+	if blocks > len(x.journal) {
+		return false
+	}
+
+	for range blocks {
+		last := len(x.journal) - 1
+		x.journal[last]()
+		x.journal = x.journal[:last]
+	}
+	clear(x.tallies)
+
+	return true
+}
+
+// addAddress adds block to blocks of address and returns function
+// removing it.
+func (x *index) addAddress(address Address, nonce uint64) func() {
+	nonces := x.addresses[address]
+	if address == "" || (len(nonces) > 0 && nonces[len(nonces)-1] == nonce) {
+		return func() {}
+	}
+
+	x.addresses[address] = append(nonces, nonce)
+	return func() {
+		if len(nonces) == 0 {
+			delete(x.addresses, address)
+		} else {
+			x.addresses[address] = nonces
+		}
+	}
+}
+
+func (x *index) tally(id string, finalized uint64) (Tally, bool) {
+	if x.finalized != finalized {
+		return Tally{}, false
+	}
+
+	tally, ok := x.tallies[id]
+	return tally, ok
+}
+
+func (x *index) cacheTally(id string, finalized uint64, tally Tally) {
+	if x.finalized != finalized {
+		clear(x.tallies)
+		x.finalized = finalized
+	}
+
+	x.tallies[id] = tally
+}
+
+// index returns index of chain blocks, it is rebuilt when blocks were
+// changed without updating it.
+func (c Chain) index() *index {
+	if c.idx.matches(c) {
+		return c.idx
+	}
+
+	idx := newIndex(c.blocks, c.Genesis())
+	if c.idx == nil {
+		return idx
+	}
+
+	*c.idx = *idx
+	return c.idx
+}
+
+// reindex rebuilds index after blocks of chain were replaced.
+func (c *Chain) reindex() {
+	c.idx = newIndex(c.blocks, c.Genesis())
+}
+
+// extendIndex adds last block of chain to index.
+func (c *Chain) extendIndex() {
+	last, _ := c.GetBlock(c.Length() - 1)
+	if c.idx == nil || c.idx.state == nil || c.idx.length != c.Length()-1 || c.idx.tip != last.PrevBlockHash {
+		c.reindex()
+		return
+	}
+
+	c.idx.apply(last)
+}
+
+func (c Chain) GetBlockByHash(blockHash string) (Block, bool) {
+	if c.blocks == nil {
+		return Block{}, false
+	}
+
+	block, err := c.blocks.GetByHash(blockHash)
+	return block, err == nil
+}
+
+// GetBlocksByAddress returns blocks signed by address or holding its
+// transactions.
+func (c Chain) GetBlocksByAddress(address Address) []Block {
+	nonces := c.index().addresses[address]
+
+	blocks := make([]Block, 0, len(nonces))
+	for _, nonce := range nonces {
+		if block, ok := c.GetBlock(int(nonce)); ok {
+			blocks = append(blocks, block)
+		}
+	}
+
+	return blocks
+}
+
+// GetVotings returns votings in order they were created.
+func (c Chain) GetVotings() []VotingWithBlock {
+	state := c.index().state
+
+	votings := make([]VotingWithBlock, 0, len(state.order))
+	for _, id := range state.order {
+		votings = append(votings, c.withBlock(*state.votings[id]))
+	}
+
+	return votings
+}
+
+func (c Chain) GetVoting(id string) (VotingWithBlock, bool) {
+	voting, ok := c.index().state.Voting(id)
+	if !ok {
+		return VotingWithBlock{}, false
+	}
+
+	return c.withBlock(voting), true
+}
+
+// GetVotingClose returns block in which creator closed voting.
+func (c Chain) GetVotingClose(id string) (Block, bool) {
+	voting, ok := c.index().state.Voting(id)
+	if !ok || !voting.Closed {
+		return Block{}, false
+	}
+
+	return c.GetBlock(int(voting.ClosedAt))
+}
+
+// withBlock returns voting together with block which created it.
+func (c Chain) withBlock(voting VotingState) VotingWithBlock {
+	block, _ := c.GetBlock(int(voting.Nonce))
+
+	return VotingWithBlock{
+		Voting:  voting.Voting,
+		Block:   block,
+		ID:      voting.ID,
+		Creator: voting.Creator,
+	}
+}
+
+// GetSequence returns sequence of last transaction of address included
+// in chain.
+func (c Chain) GetSequence(address Address) uint64 {
+	return c.index().sequences[address]
+}
+
+// GetVotes returns vote of every eligible address cast inside voting
+// window and before voting was closed. When address voted several times
+// duplicate policy of voting decides which vote is returned.
+func (c Chain) GetVotes(id string) map[Address]Vote {
+	voting, ok := c.index().state.Voting(id)
+	if !ok {
+		return make(map[Address]Vote)
+	}
+
+	return maps.Clone(voting.Ballots)
+}
@@ -0,0 +1,35 @@
+package blockchain
+
+type VotingStatus string
+
+const (
+	PendingVoting VotingStatus = "pending"
+	OpenVoting    VotingStatus = "open"
+	ClosedVoting  VotingStatus = "closed"
+)
+
+func (c Chain) GetVotingStatus(id string) (VotingStatus, bool) {
+	state, ok := c.index().state.Voting(id)
+	if !ok {
+		return "", false
+	}
+	voting := state.Voting
+
+	if state.Closed {
+		return ClosedVoting, true
+	}
+
+	lastBlock := c.GetLastBlock()
+	if voting.Ended(lastBlock) {
+		return ClosedVoting, true
+	}
+
+	if voting.StartHeight != 0 && lastBlock.Nonce+1 < voting.StartHeight {
+		return PendingVoting, true
+	}
+	if voting.StartTime != 0 && lastBlock.Timestamp < voting.StartTime {
+		return PendingVoting, true
+	}
+
+	return OpenVoting, true
+}
@@ -0,0 +1,165 @@
+package blockchain
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// Leaves and nodes of merkle trees are hashed with different prefixes,
+// so node can not be passed off as leaf.
+const (
+	merkleLeafPrefix byte = 0x00
+	merkleNodePrefix byte = 0x01
+)
+
+// MerkleRoot builds tree where every pair of nodes is hashed in order, so
+// root commits to order of leaves.
+func MerkleRoot(leaves [][32]byte) [32]byte {
+	return merkleRoot(leaves, hashPair)
+}
+
+// SetMerkleRoot builds tree where every pair of nodes is hashed in sorted
+// order. Root does not depend on order of leaves, so it commits to set of
+// them and proof is just a list of sibling hashes.
+func SetMerkleRoot(leaves [][32]byte) [32]byte {
+	return merkleRoot(leaves, hashSortedPair)
+}
+
+// SetMerkleProof returns proof of leaf with index in tree of
+// SetMerkleRoot.
+func SetMerkleProof(leaves [][32]byte, index int) [][32]byte {
+	proof := make([][32]byte, 0)
+
+	level := hashLeaves(leaves)
+	for len(level) > 1 {
+		sibling := index ^ 1
+		if sibling < len(level) {
+			proof = append(proof, level[sibling])
+		}
+
+		level = nextLevel(level, hashSortedPair)
+		index /= 2
+	}
+
+	return proof
+}
+
+func VerifySetMerkleProof(root [32]byte, leaf [32]byte, proof [][32]byte) bool {
+	hash := hashLeaf(leaf)
+	for _, sibling := range proof {
+		hash = hashSortedPair(hash, sibling)
+	}
+
+	return hash == root
+}
+
+func merkleRoot(leaves [][32]byte, pair func(a, b [32]byte) [32]byte) [32]byte {
+	if len(leaves) == 0 {
+		return [32]byte{}
+	}
+
+	level := hashLeaves(leaves)
+	for len(level) > 1 {
+		level = nextLevel(level, pair)
+	}
+
+	return level[0]
+}
+
+// nextLevel hashes pairs of nodes, the last node without pair is moved up
+// as is.
+func nextLevel(level [][32]byte, pair func(a, b [32]byte) [32]byte) [][32]byte {
+	next := make([][32]byte, 0, (len(level)+1)/2)
+	for i := 0; i < len(level); i += 2 {
+		if i+1 == len(level) {
+			next = append(next, level[i])
+			continue
+		}
+		next = append(next, pair(level[i], level[i+1]))
+	}
+
+	return next
+}
+
+func hashLeaves(leaves [][32]byte) [][32]byte {
+	hashes := make([][32]byte, 0, len(leaves))
+	for _, leaf := range leaves {
+		hashes = append(hashes, hashLeaf(leaf))
+	}
+
+	return hashes
+}
+
+func hashLeaf(leaf [32]byte) [32]byte {
+	return sha256.Sum256(append([]byte{merkleLeafPrefix}, leaf[:]...))
+}
+
+func hashPair(a, b [32]byte) [32]byte {
+	data := make([]byte, 0, 1+2*len(a))
+	data = append(data, merkleNodePrefix)
+	data = append(data, a[:]...)
+	data = append(data, b[:]...)
+
+	return sha256.Sum256(data)
+}
+
+func hashSortedPair(a, b [32]byte) [32]byte {
+	if bytes.Compare(a[:], b[:]) > 0 {
+		a, b = b, a
+	}
+
+	return hashPair(a, b)
+}
+
+func addressLeaf(address Address) [32]byte {
+	return sha256.Sum256([]byte(address))
+}
+
+func addressLeaves(addresses []Address) [][32]byte {
+	leaves := make([][32]byte, 0, len(addresses))
+	for _, address := range addresses {
+		leaves = append(leaves, addressLeaf(address))
+	}
+
+	return leaves
+}
+
+func AddressMerkleRoot(addresses []Address) string {
+	root := SetMerkleRoot(addressLeaves(addresses))
+	return hex.EncodeToString(root[:])
+}
+
+func AddressMerkleProof(addresses []Address, address Address) ([]string, bool) {
+	for i, a := range addresses {
+		if a != address {
+			continue
+		}
+
+		proof := make([]string, 0)
+		for _, hash := range SetMerkleProof(addressLeaves(addresses), i) {
+			proof = append(proof, hex.EncodeToString(hash[:]))
+		}
+		return proof, true
+	}
+
+	return nil, false
+}
+
+func VerifyAddressMerkleProof(root string, address Address, proof []string) bool {
+	rootBytes, err := hex.DecodeString(root)
+	if err != nil || len(rootBytes) != 32 {
+		return false
+	}
+
+	proofHashes := make([][32]byte, 0, len(proof))
+	for _, hash := range proof {
+		hashBytes, err := hex.DecodeString(hash)
+		if err != nil || len(hashBytes) != 32 {
+			return false
+		}
+		proofHashes = append(proofHashes, [32]byte(hashBytes))
+	}
+
+	return VerifySetMerkleProof([32]byte(rootBytes), addressLeaf(address), proofHashes)
+}
@@ -0,0 +1,99 @@
+package blockchain
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"testing"
+)
+
+func testLeaves(n int) [][32]byte {
+	leaves := make([][32]byte, 0, n)
+	for i := range n {
+		leaves = append(leaves, sha256.Sum256([]byte(fmt.Sprint(i))))
+	}
+
+	return leaves
+}
+
+func TestMerkleRootCommitsToOrder(t *testing.T) {
+	for _, n := range []int{2, 3, 4, 7} {
+		t.Run(fmt.Sprint(n), func(t *testing.T) {
+			leaves := testLeaves(n)
+			swapped := testLeaves(n)
+			swapped[0], swapped[1] = swapped[1], swapped[0]
+
+			if MerkleRoot(leaves) == MerkleRoot(swapped) {
+				t.Fatalf("root does not depend on order of leaves")
+			}
+			if SetMerkleRoot(leaves) != SetMerkleRoot(swapped) {
+				t.Fatalf("set root depends on order of leaves")
+			}
+		})
+	}
+}
+
+func TestMerkleRootSeparatesLeavesAndNodes(t *testing.T) {
+	leaves := testLeaves(4)
+
+	// root of two leaves which are nodes of the bigger tree
+	nodes := [][32]byte{
+		hashPair(hashLeaf(leaves[0]), hashLeaf(leaves[1])),
+		hashPair(hashLeaf(leaves[2]), hashLeaf(leaves[3])),
+	}
+	if MerkleRoot(nodes) == MerkleRoot(leaves) {
+		t.Fatalf("nodes are accepted as leaves")
+	}
+
+	node := hashSortedPair(hashLeaf(leaves[0]), hashLeaf(leaves[1]))
+	proof := SetMerkleProof(leaves, 0)[1:]
+	if VerifySetMerkleProof(SetMerkleRoot(leaves), node, proof) {
+		t.Fatalf("proof of node is accepted as proof of leaf")
+	}
+}
+
+func TestSetMerkleProof(t *testing.T) {
+	for _, n := range []int{1, 2, 5, 8} {
+		leaves := testLeaves(n)
+		root := SetMerkleRoot(leaves)
+
+		for i, leaf := range leaves {
+			t.Run(fmt.Sprintf("%d of %d", i, n), func(t *testing.T) {
+				proof := SetMerkleProof(leaves, i)
+				if !VerifySetMerkleProof(root, leaf, proof) {
+					t.Fatalf("valid proof is rejected")
+				}
+
+				other := sha256.Sum256([]byte("other"))
+				if VerifySetMerkleProof(root, other, proof) {
+					t.Fatalf("proof is accepted for other leaf")
+				}
+			})
+		}
+	}
+}
+
+func TestTransactionsRootCommitsToOrder(t *testing.T) {
+	wallet := NewRandomWallet()
+
+	txs := make([]Transaction, 0)
+	for sequence := range uint64(2) {
+		tx, err := NewTransaction(wallet, GenesisBlock.BlockHash, sequence+1, Call{Method: VotingMethod})
+		if err != nil {
+			t.Fatalf("failed to create transaction: %v", err)
+		}
+		txs = append(txs, tx)
+	}
+
+	root, err := TransactionsRoot(txs)
+	if err != nil {
+		t.Fatalf("failed to get root: %v", err)
+	}
+	swapped, err := TransactionsRoot([]Transaction{txs[1], txs[0]})
+	if err != nil {
+		t.Fatalf("failed to get root: %v", err)
+	}
+
+	if root == swapped {
+		t.Fatalf("reordered transactions have the same root")
+	}
+}
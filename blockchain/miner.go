@@ -0,0 +1,139 @@
+package blockchain
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// mineBatch is number of salts worker checks between looking at context
+// and reporting hashes.
+const mineBatch = 1024
+
+var ErrMiningCanceled = errors.New("mining canceled")
+
+type MineStats struct {
+	Hashes   uint64
+	Duration time.Duration
+}
+
+// HashRate returns number of hashes per second.
+func (s MineStats) HashRate() float64 {
+	if s.Duration <= 0 {
+		return 0
+	}
+	return float64(s.Hashes) / s.Duration.Seconds()
+}
+
+type mineResult struct {
+	salt uint64
+	hash [32]byte
+}
+
+// MineContext searches salt for block with given number of workers, each
+// of them scanning its own part of salt space, until block is mined or
+// ctx is canceled.
+func (b *Block) MineContext(ctx context.Context, workers int) (MineStats, error) {
+	workers = max(workers, 1)
+
+	workersCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		hashes atomic.Uint64
+		wg     sync.WaitGroup
+		errsMu sync.Mutex
+		errs   []error
+	)
+	found := make(chan mineResult, workers)
+	done := make(chan struct{})
+
+	span := math.MaxUint64 / uint64(workers)
+	started := time.Now()
+
+	for i := 0; i < workers; i++ {
+		start := uint64(i) * span
+		stop := start + span
+		if i == workers-1 {
+			stop = math.MaxUint64
+		}
+
+		data := b.BlockDataWithSalt(start)
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			var count uint64 = 0
+			defer func() { hashes.Add(count) }()
+
+			for salt := start; salt < stop; salt++ {
+				if count%mineBatch == 0 && workersCtx.Err() != nil {
+					return
+				}
+
+				data.Salt = salt
+				hash, err := data.Hash()
+				if err != nil {
+					errsMu.Lock()
+					errs = append(errs, fmt.Errorf("failed to get hash for salt %d: %v", salt, err))
+					errsMu.Unlock()
+					return
+				}
+				count++
+
+				if GetDifficulty(hash) >= data.Difficulty {
+					found <- mineResult{salt: salt, hash: hash}
+					return
+				}
+			}
+		}()
+	}
+
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	var res *mineResult
+	select {
+	case r := <-found:
+		res = &r
+	case <-workersCtx.Done():
+	case <-done:
+	}
+	cancel()
+	<-done
+
+	if res == nil {
+		select {
+		case r := <-found:
+			res = &r
+		default:
+		}
+	}
+
+	stats := MineStats{
+		Hashes:   hashes.Load(),
+		Duration: time.Since(started),
+	}
+
+	if res != nil {
+		b.Salt = res.salt
+		b.BlockHash = hex.EncodeToString(res.hash[:])
+		return stats, nil
+	}
+
+	if ctx.Err() != nil {
+		return stats, ErrMiningCanceled
+	}
+	if len(errs) > 0 {
+		return stats, errors.Join(errs...)
+	}
+	return stats, fmt.Errorf("failed to mine block")
+}
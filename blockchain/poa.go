@@ -0,0 +1,98 @@
+package blockchain
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"slices"
+)
+
+var (
+	ErrNoValidators     = errors.New("validator set is empty")
+	ErrUnknownValidator = errors.New("address is not validator")
+	ErrNotProposer      = errors.New("address is not proposer of block")
+)
+
+// ProofOfAuthority lets only validators seal blocks, taking turns in order
+// of validator set. Block with nonce n is sealed by validator
+// (n-1) mod len(Validators), so chain stalls while proposer is offline.
+type ProofOfAuthority struct {
+	Validators []Address
+}
+
+func NewProofOfAuthority(validators []Address) (ProofOfAuthority, error) {
+	if len(validators) == 0 {
+		return ProofOfAuthority{}, ErrNoValidators
+	}
+
+	for i, validator := range validators {
+		if _, err := validator.PublicKey(); err != nil {
+			return ProofOfAuthority{}, fmt.Errorf("invalid validator %s: %v", validator, err)
+		}
+		if slices.Contains(validators[:i], validator) {
+			return ProofOfAuthority{}, fmt.Errorf("duplicate validator %s", validator)
+		}
+	}
+
+	return ProofOfAuthority{
+		Validators: validators,
+	}, nil
+}
+
+func (p ProofOfAuthority) Name() string {
+	return ProofOfAuthorityConsensus
+}
+
+// Proposer returns validator which seals block with given nonce.
+func (p ProofOfAuthority) Proposer(nonce uint64) Address {
+	return p.Validators[(nonce-1)%uint64(len(p.Validators))]
+}
+
+func (p ProofOfAuthority) Prepare(chain Chain, block *Block) error {
+	if block.From != p.Proposer(block.Nonce) {
+		return fmt.Errorf("%w #%d", ErrNotProposer, block.Nonce)
+	}
+
+	block.Difficulty = 0
+	return nil
+}
+
+func (p ProofOfAuthority) Seal(ctx context.Context, block *Block) (MineStats, error) {
+	hash, err := block.BlockDataWithSalt(0).Hash()
+	if err != nil {
+		return MineStats{}, fmt.Errorf("failed to hash block: %v", err)
+	}
+
+	block.Salt = 0
+	block.BlockHash = hex.EncodeToString(hash[:])
+	return MineStats{Hashes: 1}, nil
+}
+
+func (p ProofOfAuthority) VerifySeal(block Block) error {
+	if !slices.Contains(p.Validators, block.From) {
+		return fmt.Errorf("%w: %s", ErrUnknownValidator, block.From)
+	}
+	if block.Difficulty != 0 {
+		return ErrIncorrectDifficulty
+	}
+
+	return nil
+}
+
+func (p ProofOfAuthority) VerifyHeader(chain Chain, block Block) error {
+	if err := p.VerifySeal(block); err != nil {
+		return err
+	}
+	if block.From != p.Proposer(block.Nonce) {
+		return fmt.Errorf("%w #%d", ErrNotProposer, block.Nonce)
+	}
+
+	return nil
+}
+
+// Work is the same for every block, so the longest chain wins.
+func (p ProofOfAuthority) Work(block Block) *big.Int {
+	return big.NewInt(1)
+}
@@ -0,0 +1,68 @@
+package blockchain
+
+import (
+	"context"
+	"math/big"
+	"runtime"
+)
+
+// ProofOfWork lets anyone seal block by finding salt whose block hash has
+// required number of leading zero bits.
+type ProofOfWork struct {
+	// Workers is number of goroutines mining block, GOMAXPROCS if zero
+	Workers int
+}
+
+func NewProofOfWork() ProofOfWork {
+	return ProofOfWork{}
+}
+
+func (p ProofOfWork) WithWorkers(workers int) ProofOfWork {
+	p.Workers = workers
+	return p
+}
+
+func (p ProofOfWork) Name() string {
+	return ProofOfWorkConsensus
+}
+
+func (p ProofOfWork) Prepare(chain Chain, block *Block) error {
+	block.Difficulty = chain.NextDifficulty()
+	return nil
+}
+
+func (p ProofOfWork) Seal(ctx context.Context, block *Block) (MineStats, error) {
+	workers := p.Workers
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+
+	return block.MineContext(ctx, workers)
+}
+
+func (p ProofOfWork) VerifySeal(block Block) error {
+	if block.Difficulty < MinDifficulty {
+		return ErrIncorrectDifficulty
+	}
+	if !block.HasEnoughWork() {
+		return ErrInsufficientWork
+	}
+
+	return nil
+}
+
+func (p ProofOfWork) VerifyHeader(chain Chain, block Block) error {
+	if block.Difficulty != chain.NextDifficulty() {
+		return ErrIncorrectDifficulty
+	}
+	if !block.HasEnoughWork() {
+		return ErrInsufficientWork
+	}
+
+	return nil
+}
+
+// Work returns expected number of hashes needed to mine block.
+func (p ProofOfWork) Work(block Block) *big.Int {
+	return new(big.Int).Lsh(big.NewInt(1), uint(block.Difficulty))
+}
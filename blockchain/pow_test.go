@@ -0,0 +1,137 @@
+package blockchain
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"testing"
+)
+
+// newTestBlock returns unsealed block on top of chain with header fields
+// expected by chain.
+func newTestBlock(t *testing.T, chain Chain, wallet Wallet) Block {
+	t.Helper()
+
+	block, err := NewBlockWithTransactions(chain.GetLastBlock(), wallet, nil)
+	if err != nil {
+		t.Fatalf("failed to create block: %v", err)
+	}
+	block.Difficulty = chain.NextDifficulty()
+	block.StateRoot = chain.NextStateRoot(block)
+
+	return block
+}
+
+func mineTestBlock(t *testing.T, block *Block) {
+	t.Helper()
+
+	if _, err := block.MineContext(context.Background(), 1); err != nil {
+		t.Fatalf("failed to mine block: %v", err)
+	}
+}
+
+func signTestBlock(t *testing.T, block *Block) {
+	t.Helper()
+
+	if err := block.Sign(); err != nil {
+		t.Fatalf("failed to sign block: %v", err)
+	}
+}
+
+func TestPushBlockEnforcesWork(t *testing.T) {
+	wallet := NewRandomWallet()
+
+	tests := []struct {
+		name   string
+		forge  func(t *testing.T, chain Chain, block *Block)
+		expect error
+	}{
+		{
+			name: "unmined block",
+			forge: func(t *testing.T, _ Chain, block *Block) {
+				for salt := uint64(0); ; salt++ {
+					hash, err := block.BlockDataWithSalt(salt).Hash()
+					if err != nil {
+						t.Fatalf("failed to hash block: %v", err)
+					}
+					if GetDifficulty(hash) < block.Difficulty {
+						block.Salt = salt
+						block.BlockHash = hex.EncodeToString(hash[:])
+						return
+					}
+				}
+			},
+			expect: ErrInsufficientWork,
+		},
+		{
+			name: "zero difficulty",
+			forge: func(t *testing.T, _ Chain, block *Block) {
+				block.Difficulty = 0
+				mineTestBlock(t, block)
+			},
+			expect: ErrIncorrectDifficulty,
+		},
+		{
+			name: "difficulty below next difficulty",
+			forge: func(t *testing.T, chain Chain, block *Block) {
+				block.Difficulty = chain.NextDifficulty() - 1
+				mineTestBlock(t, block)
+			},
+			expect: ErrIncorrectDifficulty,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			chain := NewChain([]Block{GenesisBlock})
+
+			block := newTestBlock(t, chain, wallet)
+			test.forge(t, chain, &block)
+			signTestBlock(t, &block)
+
+			if _, err := chain.PushBlock(block); !errors.Is(err, test.expect) {
+				t.Fatalf("expected %v, got %v", test.expect, err)
+			}
+			if chain.Length() != 1 {
+				t.Fatalf("forged block was pushed")
+			}
+		})
+	}
+}
+
+func TestPushBlockAcceptsMinedBlock(t *testing.T) {
+	chain := NewChain([]Block{GenesisBlock})
+
+	block := newTestBlock(t, chain, NewRandomWallet())
+	mineTestBlock(t, &block)
+	signTestBlock(t, &block)
+
+	if _, err := chain.PushBlock(block); err != nil {
+		t.Fatalf("failed to push mined block: %v", err)
+	}
+}
+
+func TestAddBlockRejectsLowDifficultySideBlock(t *testing.T) {
+	wallet := NewRandomWallet()
+	chain := NewChain([]Block{GenesisBlock})
+
+	tip := newTestBlock(t, chain, wallet)
+	mineTestBlock(t, &tip)
+	signTestBlock(t, &tip)
+	if _, err := chain.PushBlock(tip); err != nil {
+		t.Fatalf("failed to push mined block: %v", err)
+	}
+
+	// sibling of tip is stored aside, where only its seal is checked
+	sibling := newTestBlock(t, chain.view(1, nil), wallet)
+	sibling.Difficulty = 0
+	mineTestBlock(t, &sibling)
+	signTestBlock(t, &sibling)
+
+	if _, err := chain.AddBlock(sibling); !errors.Is(err, ErrIncorrectDifficulty) {
+		t.Fatalf("expected %v, got %v", ErrIncorrectDifficulty, err)
+	}
+	if chain.HasBlock(sibling.BlockHash) {
+		t.Fatalf("forged side block was stored")
+	}
+}
@@ -0,0 +1,216 @@
+package blockchain
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+var ErrCorruptedChain = errors.New("corrupted chain file")
+
+// Recovery describes what was dropped while recovering chain. Quarantine
+// is file holding dropped data, it is empty when nothing was dropped.
+type Recovery struct {
+	Kept       int
+	Dropped    int
+	Quarantine string
+}
+
+func (r Recovery) Recovered() bool {
+	return r.Quarantine != ""
+}
+
+// QuarantineFilepath returns new file name for data dropped from chain
+// stored at base.
+func QuarantineFilepath(base string) string {
+	return fmt.Sprintf("%s.quarantine-%d.json", base, time.Now().UnixNano())
+}
+
+// WriteFileAtomic replaces file with data, so after crash file has either
+// old or new content: data is written to temporary file, synced and
+// renamed to path.
+func WriteFileAtomic(path string, data []byte, perm os.FileMode) error {
+	dir := filepath.Dir(path)
+
+	tmp, err := os.CreateTemp(dir, filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temp file: %v", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write temp file: %v", err)
+	}
+	if err := tmp.Chmod(perm); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to chmod temp file: %v", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to sync temp file: %v", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to close temp file: %v", err)
+	}
+
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to rename temp file: %v", err)
+	}
+
+	// rename is durable only after directory is synced
+	d, err := os.Open(dir)
+	if err != nil {
+		return fmt.Errorf("failed to open dir %s: %v", dir, err)
+	}
+	defer d.Close()
+	if err := d.Sync(); err != nil {
+		return fmt.Errorf("failed to sync dir %s: %v", dir, err)
+	}
+
+	return nil
+}
+
+// RecoverChainFromFile loads chain file like NewChainFromFile, but when
+// file is truncated or corrupted it keeps blocks which can be decoded and
+// moves original file to quarantine.
+func RecoverChainFromFile(filepath string) (Chain, Recovery, error) {
+	chain, err := NewChainFromFile(filepath)
+	if err == nil {
+		return chain, Recovery{Kept: chain.Length()}, nil
+	}
+	if !errors.Is(err, ErrCorruptedChain) {
+		return Chain{}, Recovery{}, err
+	}
+
+	data, err := os.ReadFile(filepath)
+	if err != nil {
+		return Chain{}, Recovery{}, fmt.Errorf("failed to read file %s: %v", filepath, err)
+	}
+
+	blocks, dropped := decodeBlocksPrefix(data)
+	if len(blocks) == 0 {
+		blocks = []Block{GenesisBlock}
+	}
+
+	recovery := Recovery{
+		Kept:       len(blocks),
+		Dropped:    dropped,
+		Quarantine: QuarantineFilepath(filepath),
+	}
+	if err := WriteFileAtomic(recovery.Quarantine, data, 0644); err != nil {
+		return Chain{}, Recovery{}, fmt.Errorf("failed to quarantine %s: %v", filepath, err)
+	}
+
+	store := NewMemoryStore(blocks)
+	chain = Chain{
+		blocks:   store,
+		filepath: filepath,
+		side:     make(map[string]Block),
+		idx:      newIndex(store, DefaultGenesis()),
+	}
+	if err := chain.SaveFile(); err != nil {
+		return Chain{}, Recovery{}, err
+	}
+
+	return chain, recovery, nil
+}
+
+// decodeBlocksPrefix decodes blocks of json array up to first malformed
+// one and counts elements dropped after them, torn last element included.
+func decodeBlocksPrefix(data []byte) ([]Block, int) {
+	blocks := make([]Block, 0)
+	dropped := 0
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	if token, err := decoder.Token(); err != nil || token != json.Delim('[') {
+		return blocks, dropped
+	}
+
+	for decoder.More() {
+		var raw json.RawMessage
+		if err := decoder.Decode(&raw); err != nil {
+			dropped++
+			break
+		}
+
+		var block Block
+		if dropped > 0 || json.Unmarshal(raw, &block) != nil {
+			dropped++
+			continue
+		}
+		blocks = append(blocks, block)
+	}
+
+	return blocks, dropped
+}
+
+// Recover keeps the longest valid prefix of chain and moves the rest to
+// quarantine file instead of dropping the whole chain. Blocks which can not
+// be read from store are invalid as well. Chain with invalid genesis block
+// is started over from genesis. Final blocks are never dropped, chain
+// whose invalid block is final is refused.
+func (c *Chain) Recover(quarantine string) (Recovery, error) {
+	valid, idx, invalidErr := c.validPrefix()
+	if valid == c.Length() {
+		// index built while validating is kept, so blocks are not read
+		// again on first lookup
+		c.idx = idx
+		return Recovery{Kept: valid}, nil
+	}
+	if finalized := c.FinalizedHeight(); finalized > 0 && uint64(valid) <= finalized {
+		return Recovery{}, fmt.Errorf("%w: block #%d is invalid: %v", ErrFinalizedBlock, valid, invalidErr)
+	}
+
+	// blocks after unreadable one which can still be read are kept in
+	// quarantine too
+	dropped := make([]Block, 0, c.Length()-valid)
+	for nonce := valid; nonce < c.Length(); nonce++ {
+		if block, ok := c.GetBlock(nonce); ok {
+			dropped = append(dropped, block)
+		}
+	}
+
+	recovery := Recovery{
+		Kept:       max(valid, 1),
+		Dropped:    c.Length() - valid,
+		Quarantine: QuarantineFilepath(quarantine),
+	}
+	if err := writeQuarantine(recovery.Quarantine, dropped); err != nil {
+		return Recovery{}, err
+	}
+
+	var err error
+	if valid == 0 {
+		err = c.replace(0, []Block{c.GenesisBlock()})
+		idx = newIndex(c.blocks, c.Genesis())
+	} else {
+		err = c.replace(valid, nil)
+	}
+	if err != nil {
+		return Recovery{}, fmt.Errorf("failed to save chain: %w", err)
+	}
+
+	c.side = make(map[string]Block)
+	c.finalized = 0
+	c.idx = idx
+	c.updateFinalized()
+
+	return recovery, nil
+}
+
+func writeQuarantine(path string, blocks []Block) error {
+	data, err := json.Marshal(blocks)
+	if err != nil {
+		return fmt.Errorf("failed to serialize quarantined blocks: %v", err)
+	}
+	if err := WriteFileAtomic(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to quarantine blocks: %v", err)
+	}
+
+	return nil
+}
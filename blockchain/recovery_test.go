@@ -0,0 +1,185 @@
+package blockchain
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// newTestChain returns in-memory chain with n mined blocks on top of
+// genesis.
+func newTestChain(t *testing.T, n int) Chain {
+	t.Helper()
+
+	chain := NewChain([]Block{GenesisBlock})
+	for range n {
+		pushTestBlock(t, &chain, nil)
+	}
+
+	return chain
+}
+
+func TestRecoverChainFromFile(t *testing.T) {
+	blocks := newTestChain(t, 3).GetBlocks(0, 4)
+
+	elements := make([][]byte, 0, len(blocks))
+	for _, block := range blocks {
+		data, err := json.Marshal(block)
+		if err != nil {
+			t.Fatalf("failed to marshal block: %v", err)
+		}
+		elements = append(elements, data)
+	}
+	array := func(elements [][]byte) []byte {
+		return append(append([]byte("["), bytes.Join(elements, []byte(","))...), ']')
+	}
+
+	tests := []struct {
+		name    string
+		data    func() []byte
+		kept    int
+		dropped int
+	}{
+		{
+			name: "intact file",
+			data: func() []byte { return array(elements) },
+			kept: 4,
+		},
+		{
+			name: "torn last block",
+			data: func() []byte {
+				data := array(elements)
+				return data[:len(data)-10]
+			},
+			kept:    3,
+			dropped: 1,
+		},
+		{
+			name: "malformed block in the middle",
+			data: func() []byte {
+				return array([][]byte{elements[0], elements[1], []byte(`{"nonce":"x"}`), elements[3]})
+			},
+			kept:    2,
+			dropped: 2,
+		},
+		{
+			name: "not an array",
+			data: func() []byte { return []byte(`{"nonce":0}`) },
+			kept: 1,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "chain.json")
+			data := test.data()
+			if err := os.WriteFile(path, data, 0644); err != nil {
+				t.Fatalf("failed to write chain: %v", err)
+			}
+
+			chain, recovery, err := RecoverChainFromFile(path)
+			if err != nil {
+				t.Fatalf("failed to recover chain: %v", err)
+			}
+			if recovery.Kept != test.kept || recovery.Dropped != test.dropped {
+				t.Fatalf("expected %d kept and %d dropped, got %d and %d", test.kept, test.dropped, recovery.Kept, recovery.Dropped)
+			}
+			if chain.Length() != test.kept || chain.GetLastBlock().BlockHash != blocks[test.kept-1].BlockHash {
+				t.Fatalf("expected chain of %d blocks, got %d", test.kept, chain.Length())
+			}
+
+			if !recovery.Recovered() {
+				return
+			}
+			quarantined, err := os.ReadFile(recovery.Quarantine)
+			if err != nil || !bytes.Equal(quarantined, data) {
+				t.Fatalf("original file is not quarantined: %v", err)
+			}
+			reopened, err := NewChainFromFile(path)
+			if err != nil || reopened.Length() != test.kept {
+				t.Fatalf("recovered chain is not saved: %v", err)
+			}
+		})
+	}
+}
+
+func TestChainRecover(t *testing.T) {
+	tests := []struct {
+		name string
+		// tamper breaks block of chain with 3 mined blocks
+		tamper  func(blocks []Block)
+		depth   uint64
+		kept    int
+		dropped int
+		err     error
+	}{
+		{
+			name:   "valid chain",
+			tamper: func(blocks []Block) {},
+			kept:   4,
+		},
+		{
+			name:    "invalid block",
+			tamper:  func(blocks []Block) { blocks[2].Timestamp++ },
+			kept:    2,
+			dropped: 2,
+		},
+		{
+			name:    "invalid genesis block",
+			tamper:  func(blocks []Block) { blocks[0].Timestamp++ },
+			kept:    1,
+			dropped: 4,
+		},
+		{
+			name:   "invalid final block",
+			tamper: func(blocks []Block) { blocks[2].Timestamp++ },
+			depth:  1,
+			err:    ErrFinalizedBlock,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			blocks := newTestChain(t, 3).GetBlocks(0, 4)
+			test.tamper(blocks)
+			chain := NewChain(blocks).WithFinalityDepth(test.depth)
+
+			quarantine := filepath.Join(t.TempDir(), "chain")
+			recovery, err := chain.Recover(quarantine)
+			if !errors.Is(err, test.err) {
+				t.Fatalf("expected %v, got %v", test.err, err)
+			}
+			if err != nil {
+				if chain.Length() != len(blocks) {
+					t.Fatalf("refused recovery changed chain")
+				}
+				return
+			}
+
+			if recovery.Kept != test.kept || recovery.Dropped != test.dropped {
+				t.Fatalf("expected %d kept and %d dropped, got %d and %d", test.kept, test.dropped, recovery.Kept, recovery.Dropped)
+			}
+			if chain.Length() != test.kept {
+				t.Fatalf("expected chain of %d blocks, got %d", test.kept, chain.Length())
+			}
+			if ok, err := chain.Validate(); !ok || err != nil {
+				t.Fatalf("recovered chain is not valid: %v", err)
+			}
+
+			if !recovery.Recovered() {
+				return
+			}
+			var quarantined []Block
+			data, err := os.ReadFile(recovery.Quarantine)
+			if err != nil {
+				t.Fatalf("failed to read quarantine: %v", err)
+			}
+			if err := json.Unmarshal(data, &quarantined); err != nil || len(quarantined) != test.dropped {
+				t.Fatalf("expected %d quarantined blocks, got %d (err %v)", test.dropped, len(quarantined), err)
+			}
+		})
+	}
+}
@@ -0,0 +1,258 @@
+package blockchain
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"maps"
+	"slices"
+)
+
+var ErrIncorrectStateRoot = errors.New("incorrect state root")
+
+// VotingState is voting together with ballots counted so far.
+type VotingState struct {
+	ID       string           `json:"id"`
+	Creator  Address          `json:"creator"`
+	Nonce    uint64           `json:"nonce"`
+	Voting   Voting           `json:"voting"`
+	Closed   bool             `json:"closed"`
+	ClosedAt uint64           `json:"closedAt,omitempty"`
+	Ballots  map[Address]Vote `json:"ballots"`
+}
+
+// State is world state of votings built by applying calls of blocks one
+// by one, so nodes with the same chain get the same state. Root of state
+// after block is committed in header of the block.
+type State struct {
+	genesis Genesis
+	votings map[string]*VotingState
+	// order holds voting ids in order votings were created
+	order []string
+	// hashes are leaves of state root by voting id, votings changed since
+	// root was taken are dirty and hashed again by Root
+	hashes map[string][32]byte
+	dirty  map[string]bool
+}
+
+func NewState(genesis Genesis) *State {
+	return &State{
+		genesis: genesis,
+		votings: make(map[string]*VotingState),
+		order:   make([]string, 0),
+		hashes:  make(map[string][32]byte),
+		dirty:   make(map[string]bool),
+	}
+}
+
+// Voting returns state of voting, its ballots must not be modified.
+func (s *State) Voting(id string) (VotingState, bool) {
+	voting, ok := s.votings[id]
+	if !ok {
+		return VotingState{}, false
+	}
+
+	return *voting, true
+}
+
+// Root returns merkle root of hashes of all votings in order of ids.
+func (s *State) Root() string {
+	for id := range s.dirty {
+		if voting, ok := s.votings[id]; ok {
+			s.hashes[id], _ = Hash(voting)
+		} else {
+			delete(s.hashes, id)
+		}
+	}
+	clear(s.dirty)
+
+	ids := make([]string, 0, len(s.hashes))
+	for id := range s.hashes {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+
+	leaves := make([][32]byte, 0, len(ids))
+	for _, id := range ids {
+		leaves = append(leaves, s.hashes[id])
+	}
+
+	root := MerkleRoot(leaves)
+	return hex.EncodeToString(root[:])
+}
+
+// Apply changes state by calls of block and returns function reverting
+// the changes. Votings of block are created first and closes are applied
+// before votes, so votes of block closing voting are not counted.
+func (s *State) Apply(block Block) func() {
+	undo := make([]func(), 0)
+	touched := make(map[string]bool)
+
+	calls := block.Calls()
+
+	for _, call := range calls {
+		if call.Method != VotingMethod {
+			continue
+		}
+
+		var voting Voting
+		if err := json.Unmarshal(call.Data, &voting); err != nil {
+			continue
+		}
+		if _, ok := s.votings[call.ID]; ok {
+			continue
+		}
+
+		id := call.ID
+		s.votings[id] = &VotingState{
+			ID:      id,
+			Creator: call.From,
+			Nonce:   block.Nonce,
+			Voting:  voting,
+			Ballots: make(map[Address]Vote),
+		}
+		s.order = append(s.order, id)
+		touched[id] = true
+		undo = append(undo, func() {
+			delete(s.votings, id)
+			s.order = s.order[:len(s.order)-1]
+		})
+	}
+
+	for _, call := range calls {
+		if call.Method != CloseMethod {
+			continue
+		}
+
+		var closeCall Close
+		if err := json.Unmarshal(call.Data, &closeCall); err != nil {
+			continue
+		}
+
+		voting, ok := s.votings[closeCall.Voting]
+		if !ok || voting.Closed || call.From != voting.Creator {
+			continue
+		}
+
+		voting.Closed = true
+		voting.ClosedAt = block.Nonce
+		touched[voting.ID] = true
+		undo = append(undo, func() {
+			voting.Closed = false
+			voting.ClosedAt = 0
+		})
+	}
+
+	for _, call := range calls {
+		if call.Method != VoteMethod {
+			continue
+		}
+
+		var vote Vote
+		if err := json.Unmarshal(call.Data, &vote); err != nil {
+			continue
+		}
+
+		voting, ok := s.votings[vote.Voting]
+		if !ok || !s.counts(voting, block, call.From, vote) {
+			continue
+		}
+
+		from := call.From
+		prev, voted := voting.Ballots[from]
+		voting.Ballots[from] = vote
+		touched[voting.ID] = true
+		undo = append(undo, func() {
+			if voted {
+				voting.Ballots[from] = prev
+			} else {
+				delete(voting.Ballots, from)
+			}
+		})
+	}
+
+	maps.Copy(s.dirty, touched)
+
+	return func() {
+		for i := len(undo) - 1; i >= 0; i-- {
+			undo[i]()
+		}
+		maps.Copy(s.dirty, touched)
+	}
+}
+
+// counts reports whether vote included in block replaces ballot of
+// address in voting.
+func (s *State) counts(voting *VotingState, block Block, address Address, vote Vote) bool {
+	if block.Nonce <= voting.Nonce || voting.Closed || !voting.Voting.InWindow(block) {
+		return false
+	}
+	if _, err := voting.Voting.BallotChoices(vote); err != nil {
+		return false
+	}
+	if !s.IsEligible(voting.Voting, voting.Nonce, address, vote) {
+		return false
+	}
+
+	_, voted := voting.Ballots[address]
+	return !voted || voting.Voting.GetDuplicatePolicy() == LastVoteWins
+}
+
+// IsEligible reports whether address may cast vote in voting included in
+// block with given nonce.
+func (s *State) IsEligible(voting Voting, nonce uint64, address Address, vote Vote) bool {
+	electorate := voting.Electorate
+	if electorate == nil {
+		return true
+	}
+
+	if electorate.Genesis != "" {
+		named, ok := s.genesis.Electorates[electorate.Genesis]
+		if !ok {
+			return false
+		}
+		electorate = &named
+	}
+
+	if len(electorate.Addresses) > 0 {
+		return slices.Contains(electorate.Addresses, address)
+	}
+
+	if electorate.MerkleRoot != "" {
+		return VerifyAddressMerkleProof(electorate.MerkleRoot, address, vote.Proof)
+	}
+
+	if electorate.Registry != "" {
+		registry, ok := s.votings[electorate.Registry]
+		// registry must precede voting, so electorates can not form cycles
+		if !ok || registry.Nonce > nonce {
+			return false
+		}
+
+		_, ok = registry.Ballots[address]
+		return ok
+	}
+
+	return false
+}
+
+// StateRoot returns root of state after the last block of chain.
+func (c Chain) StateRoot() string {
+	return c.index().state.Root()
+}
+
+// NextStateRoot returns root of state after block is pushed on top of
+// chain, it is set in block header before block is sealed.
+func (c Chain) NextStateRoot(block Block) string {
+	state := c.index().state
+
+	revert := state.Apply(block)
+	defer revert()
+
+	return state.Root()
+}
+
+// GetVotingState returns voting with its counted ballots.
+func (c Chain) GetVotingState(id string) (VotingState, bool) {
+	return c.index().state.Voting(id)
+}
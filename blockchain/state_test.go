@@ -0,0 +1,175 @@
+package blockchain
+
+import "testing"
+
+// newStateBlock returns unsealed block with nonce holding txs, state does
+// not check seal of blocks.
+func newStateBlock(nonce uint64, txs ...Transaction) Block {
+	return Block{
+		SignBlockData: SignBlockData{BlockData: BlockData{Nonce: nonce}},
+		Transactions:  txs,
+	}
+}
+
+func TestStateApplyRevert(t *testing.T) {
+	creator := NewRandomWallet()
+	voter := NewRandomWallet()
+	voterAddress, err := voter.Address()
+	if err != nil {
+		t.Fatalf("failed to get address: %v", err)
+	}
+
+	voting := newTestTransaction(t, creator, 1, VotingMethod, NewVoting("voting"))
+	last := newTestTransaction(t, creator, 2, VotingMethod, NewVoting("last").WithDuplicatePolicy(LastVoteWins))
+	// setup is applied before every case
+	setup := []Block{
+		newStateBlock(1, voting, last),
+		newStateBlock(2, newTestTransaction(t, voter, 1, VoteMethod, Vote{Voting: last.Hash, Value: true})),
+	}
+
+	tests := []struct {
+		name  string
+		block Block
+		// changed tells whether block changes state root
+		changed bool
+		// ballots is number of ballots of voting after block
+		ballots int
+	}{
+		{
+			name:    "new voting",
+			block:   newStateBlock(3, newTestTransaction(t, creator, 3, VotingMethod, NewVoting("new"))),
+			changed: true,
+		},
+		{
+			name:    "vote",
+			block:   newStateBlock(3, newTestTransaction(t, voter, 2, VoteMethod, Vote{Voting: voting.Hash, Value: true})),
+			changed: true,
+			ballots: 1,
+		},
+		{
+			name: "vote and close in the same block",
+			block: newStateBlock(3,
+				newTestTransaction(t, voter, 2, VoteMethod, Vote{Voting: voting.Hash, Value: true}),
+				newTestTransaction(t, creator, 3, CloseMethod, NewClose(voting.Hash)),
+			),
+			changed: true,
+		},
+		{
+			name:  "close by other address",
+			block: newStateBlock(3, newTestTransaction(t, voter, 2, CloseMethod, NewClose(voting.Hash))),
+		},
+		{
+			name:    "vote replacing ballot",
+			block:   newStateBlock(3, newTestTransaction(t, voter, 2, VoteMethod, Vote{Voting: last.Hash, Value: false})),
+			changed: true,
+		},
+		{
+			name:  "invalid choice",
+			block: newStateBlock(3, newTestTransaction(t, voter, 2, VoteMethod, Vote{Voting: voting.Hash, Choices: []int{5}})),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			state := NewState(Genesis{})
+			for _, block := range setup {
+				state.Apply(block)
+			}
+			root := state.Root()
+
+			revert := state.Apply(test.block)
+			applied := state.Root()
+			if (applied != root) != test.changed {
+				t.Fatalf("expected state root to change %v, got %s -> %s", test.changed, root, applied)
+			}
+			if votingState, _ := state.Voting(voting.Hash); len(votingState.Ballots) != test.ballots {
+				t.Fatalf("expected %d ballots, got %d", test.ballots, len(votingState.Ballots))
+			}
+
+			// root of incrementally hashed state equals root of state
+			// replayed from scratch
+			replayed := NewState(Genesis{})
+			for _, block := range append(setup, test.block) {
+				replayed.Apply(block)
+			}
+			if replayed.Root() != applied {
+				t.Fatalf("incremental root %s differs from replayed root %s", applied, replayed.Root())
+			}
+
+			revert()
+			if state.Root() != root {
+				t.Fatalf("state root is not restored after revert")
+			}
+			votingState, ok := state.Voting(voting.Hash)
+			if !ok || votingState.Closed || len(votingState.Ballots) != 0 {
+				t.Fatalf("voting is not restored after revert: %+v", votingState)
+			}
+			if lastState, _ := state.Voting(last.Hash); lastState.Ballots[voterAddress].Value != true {
+				t.Fatalf("ballot of setup is changed by revert")
+			}
+		})
+	}
+}
+
+func TestStateRevertRestoresReplacedBallot(t *testing.T) {
+	creator := NewRandomWallet()
+	voter := NewRandomWallet()
+	voterAddress, err := voter.Address()
+	if err != nil {
+		t.Fatalf("failed to get address: %v", err)
+	}
+
+	voting := newTestTransaction(t, creator, 1, VotingMethod, NewVoting("voting").WithDuplicatePolicy(LastVoteWins))
+	state := NewState(Genesis{})
+	state.Apply(newStateBlock(1, voting))
+	state.Apply(newStateBlock(2, newTestTransaction(t, voter, 1, VoteMethod, Vote{Voting: voting.Hash, Value: true})))
+	root := state.Root()
+
+	// reverts are run in reverse order of blocks, like reorg does
+	reverts := []func(){
+		state.Apply(newStateBlock(3, newTestTransaction(t, voter, 2, VoteMethod, Vote{Voting: voting.Hash, Value: false}))),
+		state.Apply(newStateBlock(4, newTestTransaction(t, creator, 2, CloseMethod, NewClose(voting.Hash)))),
+	}
+	if votingState, _ := state.Voting(voting.Hash); !votingState.Closed || votingState.Ballots[voterAddress].Value {
+		t.Fatalf("ballot is not replaced or voting is not closed")
+	}
+
+	for i := len(reverts) - 1; i >= 0; i-- {
+		reverts[i]()
+	}
+
+	votingState, _ := state.Voting(voting.Hash)
+	if votingState.Closed || !votingState.Ballots[voterAddress].Value {
+		t.Fatalf("replaced ballot is not restored")
+	}
+	if state.Root() != root {
+		t.Fatalf("state root is not restored")
+	}
+}
+
+func TestNextStateRootKeepsState(t *testing.T) {
+	chain := NewChain([]Block{GenesisBlock})
+	voting := newTestTransaction(t, NewRandomWallet(), 1, VotingMethod, NewVoting("voting"))
+
+	root := chain.StateRoot()
+	block, err := NewBlockWithTransactions(chain.GetLastBlock(), NewRandomWallet(), []Transaction{voting})
+	if err != nil {
+		t.Fatalf("failed to create block: %v", err)
+	}
+	next := chain.NextStateRoot(block)
+
+	if next == root {
+		t.Fatalf("next state root does not commit to voting")
+	}
+	if chain.StateRoot() != root {
+		t.Fatalf("next state root changed state of chain")
+	}
+	if _, ok := chain.GetVotingState(voting.Hash); ok {
+		t.Fatalf("voting of unsealed block is kept")
+	}
+
+	pushed := pushTestBlock(t, &chain, []Transaction{voting})
+	if pushed.StateRoot != next || chain.StateRoot() != next {
+		t.Fatalf("state root of pushed block differs from next state root")
+	}
+}
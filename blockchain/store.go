@@ -0,0 +1,434 @@
+package blockchain
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"os"
+	"path/filepath"
+	"slices"
+)
+
+const (
+	BlockLogFile   = "blocks.log"
+	BlockIndexFile = "blocks.idx"
+
+	// record of log is length and crc32 of block json followed by json
+	recordHeaderSize = 8
+	// entry of index is offset of record in log followed by block hash
+	indexEntrySize = 8 + 32
+)
+
+var (
+	ErrBlockNotFound  = errors.New("block not found")
+	ErrCorruptedStore = errors.New("corrupted block store")
+	ErrReadOnlyStore  = errors.New("store is read-only")
+)
+
+// Store keeps blocks of main chain in order of nonces, chain reads blocks
+// through store instead of keeping them in memory.
+type Store interface {
+	Length() int
+	Get(nonce uint64) (Block, error)
+	GetByHash(blockHash string) (Block, error)
+	Append(block Block) error
+	// Truncate removes all blocks starting from nonce length
+	Truncate(length int) error
+	Close() error
+}
+
+// FileStore is append-only log of blocks with index file holding offset
+// and hash of every block, so blocks are found without reading log.
+// Log is always written before index and truncated after it, so after
+// crash unindexed tail of log is dropped on open.
+type FileStore struct {
+	log   *os.File
+	index *os.File
+
+	offsets []int64
+	hashes  map[string]uint64
+	size    int64
+	// last is decoded block at the tip, which is read most often
+	last Block
+}
+
+var _ Store = (*FileStore)(nil)
+
+func OpenFileStore(dir string) (*FileStore, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create store dir %s: %v", dir, err)
+	}
+
+	log, err := os.OpenFile(filepath.Join(dir, BlockLogFile), os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open block log: %v", err)
+	}
+	index, err := os.OpenFile(filepath.Join(dir, BlockIndexFile), os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		log.Close()
+		return nil, fmt.Errorf("failed to open block index: %v", err)
+	}
+
+	s := &FileStore{
+		log:     log,
+		index:   index,
+		offsets: make([]int64, 0),
+		hashes:  make(map[string]uint64),
+	}
+	if err := s.recover(); err != nil {
+		s.Close()
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// recover reads index and drops entries and log records which were not
+// completely written.
+func (s *FileStore) recover() error {
+	data, err := os.ReadFile(s.index.Name())
+	if err != nil {
+		return fmt.Errorf("failed to read block index: %v", err)
+	}
+
+	logInfo, err := s.log.Stat()
+	if err != nil {
+		return fmt.Errorf("failed to stat block log: %v", err)
+	}
+	logSize := logInfo.Size()
+
+	entries := len(data) / indexEntrySize
+	for i := 0; i < entries; i++ {
+		entry := data[i*indexEntrySize : (i+1)*indexEntrySize]
+		offset := int64(binary.BigEndian.Uint64(entry[:8]))
+		if offset >= logSize || (i > 0 && offset <= s.offsets[i-1]) {
+			break
+		}
+
+		s.offsets = append(s.offsets, offset)
+		s.hashes[hex.EncodeToString(entry[8:])] = uint64(i)
+	}
+
+	// only tail can be damaged by crash, so only last record is checked
+	for len(s.offsets) > 0 {
+		last := len(s.offsets) - 1
+		block, size, err := s.readBlock(s.offsets[last], logSize)
+		if err == nil {
+			s.last = block
+			s.size = s.offsets[last] + size
+			break
+		}
+		s.dropLast()
+	}
+
+	if err := s.index.Truncate(int64(len(s.offsets) * indexEntrySize)); err != nil {
+		return fmt.Errorf("failed to truncate block index: %v", err)
+	}
+	if err := s.log.Truncate(s.size); err != nil {
+		return fmt.Errorf("failed to truncate block log: %v", err)
+	}
+
+	return nil
+}
+
+func (s *FileStore) dropLast() {
+	last := len(s.offsets) - 1
+	for hash, nonce := range s.hashes {
+		if nonce == uint64(last) {
+			delete(s.hashes, hash)
+		}
+	}
+	s.offsets = s.offsets[:last]
+}
+
+// readBlock decodes block of record at offset and returns it with size of
+// record.
+func (s *FileStore) readBlock(offset int64, logSize int64) (Block, int64, error) {
+	data, err := s.readRecord(offset, logSize)
+	if err != nil {
+		return Block{}, 0, err
+	}
+
+	var block Block
+	if err := json.Unmarshal(data, &block); err != nil {
+		return Block{}, 0, fmt.Errorf("%w: failed to deserialize block at %d: %v", ErrCorruptedStore, offset, err)
+	}
+
+	return block, int64(recordHeaderSize + len(data)), nil
+}
+
+func (s *FileStore) readRecord(offset int64, logSize int64) ([]byte, error) {
+	header := make([]byte, recordHeaderSize)
+	if offset+recordHeaderSize > logSize {
+		return nil, fmt.Errorf("%w: truncated record at %d", ErrCorruptedStore, offset)
+	}
+	if _, err := s.log.ReadAt(header, offset); err != nil {
+		return nil, fmt.Errorf("failed to read record at %d: %v", offset, err)
+	}
+
+	length := int64(binary.BigEndian.Uint32(header[:4]))
+	checksum := binary.BigEndian.Uint32(header[4:])
+	if offset+recordHeaderSize+length > logSize {
+		return nil, fmt.Errorf("%w: truncated record at %d", ErrCorruptedStore, offset)
+	}
+
+	data := make([]byte, length)
+	if _, err := s.log.ReadAt(data, offset+recordHeaderSize); err != nil {
+		return nil, fmt.Errorf("failed to read record at %d: %v", offset, err)
+	}
+	if crc32.ChecksumIEEE(data) != checksum {
+		return nil, fmt.Errorf("%w: bad checksum of record at %d", ErrCorruptedStore, offset)
+	}
+
+	return data, nil
+}
+
+func (s *FileStore) Length() int {
+	return len(s.offsets)
+}
+
+func (s *FileStore) Get(nonce uint64) (Block, error) {
+	if nonce >= uint64(len(s.offsets)) {
+		return Block{}, fmt.Errorf("%w: #%d", ErrBlockNotFound, nonce)
+	}
+	if nonce == uint64(len(s.offsets)-1) {
+		return s.last, nil
+	}
+
+	block, _, err := s.readBlock(s.offsets[nonce], s.size)
+	if err != nil {
+		return Block{}, fmt.Errorf("failed to read block #%d: %w", nonce, err)
+	}
+
+	return block, nil
+}
+
+func (s *FileStore) GetByHash(blockHash string) (Block, error) {
+	nonce, ok := s.hashes[blockHash]
+	if !ok {
+		return Block{}, fmt.Errorf("%w: %s", ErrBlockNotFound, blockHash)
+	}
+
+	return s.Get(nonce)
+}
+
+func (s *FileStore) Append(block Block) error {
+	hash, err := hex.DecodeString(block.BlockHash)
+	if err != nil || len(hash) != 32 {
+		return fmt.Errorf("invalid block hash %s", block.BlockHash)
+	}
+
+	data, err := json.Marshal(block)
+	if err != nil {
+		return fmt.Errorf("failed to serialize block: %v", err)
+	}
+
+	record := make([]byte, recordHeaderSize, recordHeaderSize+len(data))
+	binary.BigEndian.PutUint32(record[:4], uint32(len(data)))
+	binary.BigEndian.PutUint32(record[4:], crc32.ChecksumIEEE(data))
+	record = append(record, data...)
+
+	offset := s.size
+	if _, err := s.log.WriteAt(record, offset); err != nil {
+		return fmt.Errorf("failed to write block log: %v", err)
+	}
+	if err := s.log.Sync(); err != nil {
+		return fmt.Errorf("failed to sync block log: %v", err)
+	}
+
+	entry := make([]byte, 8, indexEntrySize)
+	binary.BigEndian.PutUint64(entry, uint64(offset))
+	entry = append(entry, hash...)
+
+	if _, err := s.index.WriteAt(entry, int64(len(s.offsets)*indexEntrySize)); err != nil {
+		return fmt.Errorf("failed to write block index: %v", err)
+	}
+	if err := s.index.Sync(); err != nil {
+		return fmt.Errorf("failed to sync block index: %v", err)
+	}
+
+	s.hashes[block.BlockHash] = uint64(len(s.offsets))
+	s.offsets = append(s.offsets, offset)
+	s.size = offset + int64(len(record))
+	s.last = block
+
+	return nil
+}
+
+func (s *FileStore) Truncate(length int) error {
+	if length >= len(s.offsets) {
+		return nil
+	}
+
+	if err := s.index.Truncate(int64(length * indexEntrySize)); err != nil {
+		return fmt.Errorf("failed to truncate block index: %v", err)
+	}
+	if err := s.index.Sync(); err != nil {
+		return fmt.Errorf("failed to sync block index: %v", err)
+	}
+
+	size := s.offsets[length]
+	if err := s.log.Truncate(size); err != nil {
+		return fmt.Errorf("failed to truncate block log: %v", err)
+	}
+	if err := s.log.Sync(); err != nil {
+		return fmt.Errorf("failed to sync block log: %v", err)
+	}
+
+	for hash, nonce := range s.hashes {
+		if nonce >= uint64(length) {
+			delete(s.hashes, hash)
+		}
+	}
+	s.offsets = s.offsets[:length]
+	s.size = size
+
+	s.last = Block{}
+	if length > 0 {
+		last, _, err := s.readBlock(s.offsets[length-1], s.size)
+		if err != nil {
+			return fmt.Errorf("failed to read block #%d: %w", length-1, err)
+		}
+		s.last = last
+	}
+
+	return nil
+}
+
+func (s *FileStore) Close() error {
+	logErr := s.log.Close()
+	indexErr := s.index.Close()
+
+	return errors.Join(logErr, indexErr)
+}
+
+// MemoryStore keeps blocks in memory, it holds blocks of chains saved to
+// single file and of chains which are not saved at all.
+type MemoryStore struct {
+	blocks []Block
+	hashes map[string]uint64
+}
+
+var _ Store = (*MemoryStore)(nil)
+
+func NewMemoryStore(blocks []Block) *MemoryStore {
+	s := &MemoryStore{
+		blocks: make([]Block, 0, len(blocks)),
+		hashes: make(map[string]uint64),
+	}
+	for _, block := range blocks {
+		s.Append(block)
+	}
+
+	return s
+}
+
+func (s *MemoryStore) Length() int {
+	return len(s.blocks)
+}
+
+func (s *MemoryStore) Get(nonce uint64) (Block, error) {
+	if nonce >= uint64(len(s.blocks)) {
+		return Block{}, fmt.Errorf("%w: #%d", ErrBlockNotFound, nonce)
+	}
+
+	return s.blocks[nonce], nil
+}
+
+func (s *MemoryStore) GetByHash(blockHash string) (Block, error) {
+	nonce, ok := s.hashes[blockHash]
+	if !ok {
+		return Block{}, fmt.Errorf("%w: %s", ErrBlockNotFound, blockHash)
+	}
+
+	return s.blocks[nonce], nil
+}
+
+func (s *MemoryStore) Append(block Block) error {
+	// repeated block, which only invalid chain may hold, is found by hash
+	// as its first copy
+	if _, ok := s.hashes[block.BlockHash]; !ok {
+		s.hashes[block.BlockHash] = uint64(len(s.blocks))
+	}
+	s.blocks = append(s.blocks, block)
+
+	return nil
+}
+
+func (s *MemoryStore) Truncate(length int) error {
+	if length >= len(s.blocks) {
+		return nil
+	}
+
+	for _, block := range s.blocks[length:] {
+		if nonce, ok := s.hashes[block.BlockHash]; ok && nonce >= uint64(length) {
+			delete(s.hashes, block.BlockHash)
+		}
+	}
+	s.blocks = slices.Clip(s.blocks[:length])
+
+	return nil
+}
+
+func (s *MemoryStore) Close() error {
+	return nil
+}
+
+// viewStore is read-only store of the first length blocks of base followed
+// by tail, so blocks can be validated on top of part of chain or on top of
+// branch which is not stored yet without copying chain.
+type viewStore struct {
+	base   Store
+	length int
+	tail   []Block
+}
+
+var _ Store = viewStore{}
+
+func (s viewStore) Length() int {
+	return s.length + len(s.tail)
+}
+
+func (s viewStore) Get(nonce uint64) (Block, error) {
+	if nonce < uint64(s.length) {
+		return s.base.Get(nonce)
+	}
+	if i := nonce - uint64(s.length); i < uint64(len(s.tail)) {
+		return s.tail[i], nil
+	}
+
+	return Block{}, fmt.Errorf("%w: #%d", ErrBlockNotFound, nonce)
+}
+
+func (s viewStore) GetByHash(blockHash string) (Block, error) {
+	for _, block := range s.tail {
+		if block.BlockHash == blockHash {
+			return block, nil
+		}
+	}
+
+	block, err := s.base.GetByHash(blockHash)
+	if err != nil {
+		return Block{}, err
+	}
+	if block.Nonce >= uint64(s.length) {
+		return Block{}, fmt.Errorf("%w: %s", ErrBlockNotFound, blockHash)
+	}
+
+	return block, nil
+}
+
+func (s viewStore) Append(Block) error {
+	return ErrReadOnlyStore
+}
+
+func (s viewStore) Truncate(int) error {
+	return ErrReadOnlyStore
+}
+
+func (s viewStore) Close() error {
+	return nil
+}
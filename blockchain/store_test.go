@@ -0,0 +1,242 @@
+package blockchain
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// countingStore counts blocks read from store.
+type countingStore struct {
+	Store
+	reads int
+}
+
+func (s *countingStore) Get(nonce uint64) (Block, error) {
+	s.reads++
+	return s.Store.Get(nonce)
+}
+
+func (s *countingStore) GetByHash(blockHash string) (Block, error) {
+	s.reads++
+	return s.Store.GetByHash(blockHash)
+}
+
+func openTestStore(t *testing.T, dir string) *FileStore {
+	t.Helper()
+
+	store, err := OpenFileStore(dir)
+	if err != nil {
+		t.Fatalf("failed to open store: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+
+	return store
+}
+
+// newStoreChain returns chain on top of file store in dir with n mined
+// blocks holding votings.
+func newStoreChain(t *testing.T, dir string, n int) Chain {
+	t.Helper()
+
+	chain, err := NewChainFromStore(openTestStore(t, dir))
+	if err != nil {
+		t.Fatalf("failed to open chain: %v", err)
+	}
+
+	wallet := NewRandomWallet()
+	for i := range n {
+		tx := newTestTransaction(t, wallet, uint64(i)+1, VotingMethod, NewVoting("voting"))
+		pushTestBlock(t, &chain, []Transaction{tx})
+	}
+
+	return chain
+}
+
+func TestFileStore(t *testing.T) {
+	tests := []struct {
+		name string
+		// damage changes files of store with n blocks before it is
+		// opened again
+		damage func(t *testing.T, dir string)
+		length int
+	}{
+		{
+			name:   "reopened",
+			damage: func(t *testing.T, dir string) {},
+			length: 4,
+		},
+		{
+			name: "torn tail of log",
+			damage: func(t *testing.T, dir string) {
+				truncateFile(t, filepath.Join(dir, BlockLogFile), 10)
+			},
+			length: 3,
+		},
+		{
+			name: "torn tail of index",
+			damage: func(t *testing.T, dir string) {
+				truncateFile(t, filepath.Join(dir, BlockIndexFile), 1)
+			},
+			length: 3,
+		},
+		{
+			name: "unindexed record",
+			damage: func(t *testing.T, dir string) {
+				log, err := os.OpenFile(filepath.Join(dir, BlockLogFile), os.O_APPEND|os.O_WRONLY, 0644)
+				if err != nil {
+					t.Fatalf("failed to open log: %v", err)
+				}
+				defer log.Close()
+				if _, err := log.Write([]byte("garbage")); err != nil {
+					t.Fatalf("failed to write log: %v", err)
+				}
+			},
+			length: 4,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dir := t.TempDir()
+			chain := newStoreChain(t, dir, 3)
+			blocks := chain.GetBlocks(0, chain.Length())
+			if err := chain.Close(); err != nil {
+				t.Fatalf("failed to close store: %v", err)
+			}
+
+			test.damage(t, dir)
+
+			store := openTestStore(t, dir)
+			if store.Length() != test.length {
+				t.Fatalf("expected %d blocks, got %d", test.length, store.Length())
+			}
+			for nonce, expect := range blocks[:test.length] {
+				block, err := store.Get(uint64(nonce))
+				if err != nil || block.BlockHash != expect.BlockHash {
+					t.Fatalf("expected block %s, got %s (err %v)", expect.BlockHash, block.BlockHash, err)
+				}
+				if block, err := store.GetByHash(expect.BlockHash); err != nil || block.Nonce != uint64(nonce) {
+					t.Fatalf("expected block #%d by hash, got #%d (err %v)", nonce, block.Nonce, err)
+				}
+			}
+			for _, dropped := range blocks[test.length:] {
+				if _, err := store.GetByHash(dropped.BlockHash); !errors.Is(err, ErrBlockNotFound) {
+					t.Fatalf("expected %v for dropped block, got %v", ErrBlockNotFound, err)
+				}
+			}
+
+			// store keeps appending after recovered tail
+			if err := store.Append(blocks[len(blocks)-1]); err != nil {
+				t.Fatalf("failed to append block: %v", err)
+			}
+			if block, err := store.Get(uint64(test.length)); err != nil || block.BlockHash != blocks[len(blocks)-1].BlockHash {
+				t.Fatalf("appended block is not read back: %v", err)
+			}
+		})
+	}
+}
+
+func truncateFile(t *testing.T, path string, cut int64) {
+	t.Helper()
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("failed to stat %s: %v", path, err)
+	}
+	if err := os.Truncate(path, info.Size()-cut); err != nil {
+		t.Fatalf("failed to truncate %s: %v", path, err)
+	}
+}
+
+func TestChainFromStoreReadsBlocksOnLookup(t *testing.T) {
+	dir := t.TempDir()
+	chain := newStoreChain(t, dir, 3)
+	tip := chain.GetLastBlock()
+	root := chain.StateRoot()
+	second, _ := chain.GetBlock(1)
+	chain.Close()
+
+	store := &countingStore{Store: openTestStore(t, dir)}
+	reopened, err := NewChainFromStore(store)
+	if err != nil {
+		t.Fatalf("failed to open chain: %v", err)
+	}
+	if store.reads != 0 || reopened.Length() != 4 {
+		t.Fatalf("expected 4 blocks without reading them, got %d with %d reads", reopened.Length(), store.reads)
+	}
+
+	tests := []struct {
+		name  string
+		check func(chain Chain) bool
+		reads int
+	}{
+		{
+			name:  "tip",
+			check: func(chain Chain) bool { return chain.GetLastBlock().BlockHash == tip.BlockHash },
+			reads: 1,
+		},
+		{
+			name: "block by hash",
+			check: func(chain Chain) bool {
+				block, ok := chain.GetBlockByHash(second.BlockHash)
+				return ok && block.Nonce == 1
+			},
+			reads: 1,
+		},
+		{
+			name: "block by nonce",
+			check: func(chain Chain) bool {
+				block, ok := chain.GetBlock(1)
+				return ok && block.BlockHash == second.BlockHash
+			},
+			reads: 1,
+		},
+		{
+			name:  "state built on first lookup",
+			check: func(chain Chain) bool { return chain.StateRoot() == root },
+			reads: -1,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			store.reads = 0
+			if !test.check(reopened) {
+				t.Fatalf("lookup does not match chain before reopening")
+			}
+			if test.reads >= 0 && store.reads != test.reads {
+				t.Fatalf("expected %d blocks read, got %d", test.reads, store.reads)
+			}
+		})
+	}
+}
+
+func TestStoreChainReorg(t *testing.T) {
+	dir := t.TempDir()
+	chain := newStoreChain(t, dir, 2)
+
+	branch := mineBranch(t, chain, 1, 3)
+	for _, block := range branch {
+		if _, err := chain.AddBlock(block); err != nil {
+			t.Fatalf("failed to add block: %v", err)
+		}
+	}
+	root := chain.StateRoot()
+	chain.Close()
+
+	reopened, err := NewChainFromStore(openTestStore(t, dir))
+	if err != nil {
+		t.Fatalf("failed to open chain: %v", err)
+	}
+	if reopened.GetLastBlock().BlockHash != branch[len(branch)-1].BlockHash {
+		t.Fatalf("reorganized chain is not saved")
+	}
+	if ok, err := reopened.Validate(); !ok || err != nil {
+		t.Fatalf("saved chain is not valid: %v", err)
+	}
+	if reopened.StateRoot() != root {
+		t.Fatalf("state of saved chain differs")
+	}
+}
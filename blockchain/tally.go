@@ -0,0 +1,173 @@
+package blockchain
+
+import "errors"
+
+const NoWinner = -1
+
+var ErrVotingNotFound = errors.New("voting not found")
+
+type Tally struct {
+	Options []string `json:"options"`
+	// Counts holds approvals per option, for ranked votings only first
+	// preferences are counted.
+	Counts  []int `json:"counts"`
+	Ballots int   `json:"ballots"`
+	Invalid int   `json:"invalid"`
+	// Rounds holds counts of every instant-runoff round, eliminated
+	// options have zero count.
+	Rounds [][]int `json:"rounds,omitempty"`
+	Winner int     `json:"winner"`
+
+	Status VotingStatus `json:"status"`
+	// Final is set when no more votes can be counted, so tally will not
+	// change anymore
+	Final bool `json:"final"`
+	// Finalized is set when voting ended in final block, so tally can not
+	// be changed by reorg either
+	Finalized bool `json:"finalized"`
+}
+
+func (t Tally) WinnerOption() (string, bool) {
+	if t.Winner == NoWinner {
+		return "", false
+	}
+
+	return t.Options[t.Winner], true
+}
+
+// GetTally returns tally of voting, it is counted once per chain tip and
+// then taken from index.
+func (c Chain) GetTally(id string) (Tally, error) {
+	idx := c.index()
+	finalized := c.FinalizedHeight()
+	if tally, ok := idx.tally(id, finalized); ok {
+		return tally, nil
+	}
+
+	voting, ok := c.GetVoting(id)
+	if !ok {
+		return Tally{}, ErrVotingNotFound
+	}
+
+	ballots := make([][]int, 0)
+	invalid := 0
+	for _, vote := range c.GetVotes(id) {
+		choices, err := voting.BallotChoices(vote)
+		if err != nil {
+			invalid++
+			continue
+		}
+		ballots = append(ballots, choices)
+	}
+
+	status, _ := c.GetVotingStatus(id)
+
+	tally := NewTally(voting.Voting, ballots)
+	tally.Invalid = invalid
+	tally.Status = status
+	tally.Final = status == ClosedVoting
+	tally.Finalized = tally.Final && c.IsVotingFinal(id)
+
+	idx.cacheTally(id, finalized, tally)
+	return tally, nil
+}
+
+// NewTally counts already validated ballots of voting.
+func NewTally(voting Voting, ballots [][]int) Tally {
+	options := voting.AllOptions()
+	tally := Tally{
+		Options: options,
+		Counts:  make([]int, len(options)),
+		Ballots: len(ballots),
+		Winner:  NoWinner,
+	}
+
+	for _, choices := range ballots {
+		if voting.GetKind() == RankedChoiceVoting {
+			tally.Counts[choices[0]]++
+			continue
+		}
+		for _, choice := range choices {
+			tally.Counts[choice]++
+		}
+	}
+
+	if voting.GetKind() == RankedChoiceVoting {
+		tally.Rounds, tally.Winner = instantRunoff(len(options), ballots)
+	} else {
+		tally.Winner = maxCount(tally.Counts)
+	}
+
+	return tally
+}
+
+// maxCount returns index of the only option with highest non zero count.
+func maxCount(counts []int) int {
+	winner := NoWinner
+	best := 0
+	for i, count := range counts {
+		if count > best {
+			winner = i
+			best = count
+		} else if count == best {
+			winner = NoWinner
+		}
+	}
+
+	return winner
+}
+
+func instantRunoff(options int, ballots [][]int) ([][]int, int) {
+	rounds := make([][]int, 0)
+	eliminated := make([]bool, options)
+	remaining := options
+
+	for remaining > 0 {
+		counts := make([]int, options)
+		active := 0
+		for _, choices := range ballots {
+			for _, choice := range choices {
+				if !eliminated[choice] {
+					counts[choice]++
+					active++
+					break
+				}
+			}
+		}
+		rounds = append(rounds, counts)
+
+		if active == 0 {
+			return rounds, NoWinner
+		}
+
+		for i, count := range counts {
+			if count*2 > active {
+				return rounds, i
+			}
+		}
+
+		// eliminate option with lowest count, ties are broken by first
+		// round count and then by later position in options
+		loser := NoWinner
+		for i := options - 1; i >= 0; i-- {
+			if eliminated[i] {
+				continue
+			}
+			if loser == NoWinner ||
+				counts[i] < counts[loser] ||
+				(counts[i] == counts[loser] && rounds[0][i] < rounds[0][loser]) {
+				loser = i
+			}
+		}
+
+		// all remaining options are tied
+		if remaining > 1 && counts[loser]*remaining == active {
+			return rounds, NoWinner
+		}
+
+		eliminated[loser] = true
+		remaining--
+	}
+
+	return rounds, NoWinner
+}
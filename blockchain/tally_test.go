@@ -0,0 +1,270 @@
+package blockchain
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+// repeat returns n copies of ballot.
+func repeat(n int, ballot []int) [][]int {
+	ballots := make([][]int, 0, n)
+	for range n {
+		ballots = append(ballots, ballot)
+	}
+
+	return ballots
+}
+
+func TestNewTally(t *testing.T) {
+	options := []string{"a", "b", "c", "d"}
+
+	tests := []struct {
+		name    string
+		voting  Voting
+		ballots [][]int
+		counts  []int
+		rounds  [][]int
+		winner  int
+	}{
+		{
+			name:    "yes no voting",
+			voting:  NewVoting("voting"),
+			ballots: slices.Concat(repeat(2, []int{0}), repeat(1, []int{1})),
+			counts:  []int{2, 1},
+			winner:  0,
+		},
+		{
+			name:    "tie has no winner",
+			voting:  NewVoting("voting"),
+			ballots: slices.Concat(repeat(2, []int{0}), repeat(2, []int{1})),
+			counts:  []int{2, 2},
+			winner:  NoWinner,
+		},
+		{
+			name:   "no ballots",
+			voting: NewVoting("voting"),
+			counts: []int{0, 0},
+			winner: NoWinner,
+		},
+		{
+			name:    "multi choice counts approvals",
+			voting:  NewVotingWithOptions("voting", MultiChoiceVoting, options[:3], 2),
+			ballots: [][]int{{0, 1}, {1, 2}, {1}},
+			counts:  []int{1, 3, 1},
+			winner:  1,
+		},
+		{
+			name:    "ranked majority in first round",
+			voting:  NewVotingWithOptions("voting", RankedChoiceVoting, options[:3], 0),
+			ballots: [][]int{{0, 1}, {0, 2}, {1, 0}},
+			counts:  []int{2, 1, 0},
+			rounds:  [][]int{{2, 1, 0}},
+			winner:  0,
+		},
+		{
+			name:    "ranked ballot moves to next preference",
+			voting:  NewVotingWithOptions("voting", RankedChoiceVoting, options[:3], 0),
+			ballots: slices.Concat(repeat(2, []int{0}), repeat(2, []int{1}), repeat(1, []int{2, 1})),
+			counts:  []int{2, 2, 1},
+			rounds:  [][]int{{2, 2, 1}, {2, 3, 0}},
+			winner:  1,
+		},
+		{
+			name:    "ranked tie eliminates later option",
+			voting:  NewVotingWithOptions("voting", RankedChoiceVoting, options[:3], 0),
+			ballots: [][]int{{0}, {0}, {1}, {2, 1}},
+			counts:  []int{2, 1, 1},
+			rounds:  [][]int{{2, 1, 1}, {2, 2, 0}},
+			winner:  NoWinner,
+		},
+		{
+			name:   "ranked tie eliminates option with fewer first preferences",
+			voting: NewVotingWithOptions("voting", RankedChoiceVoting, options, 0),
+			ballots: slices.Concat(
+				repeat(4, []int{0}),
+				repeat(2, []int{1, 2}),
+				repeat(3, []int{2}),
+				repeat(1, []int{3, 1}),
+			),
+			counts: []int{4, 2, 3, 1},
+			rounds: [][]int{{4, 2, 3, 1}, {4, 3, 3, 0}, {4, 0, 5, 0}},
+			winner: 2,
+		},
+		{
+			name:   "ranked without ballots",
+			voting: NewVotingWithOptions("voting", RankedChoiceVoting, options[:3], 0),
+			counts: []int{0, 0, 0},
+			rounds: [][]int{{0, 0, 0}},
+			winner: NoWinner,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			tally := NewTally(test.voting, test.ballots)
+
+			if !slices.Equal(tally.Counts, test.counts) {
+				t.Fatalf("expected counts %v, got %v", test.counts, tally.Counts)
+			}
+			if !slices.EqualFunc(tally.Rounds, test.rounds, slices.Equal) {
+				t.Fatalf("expected rounds %v, got %v", test.rounds, tally.Rounds)
+			}
+			if tally.Winner != test.winner || tally.Ballots != len(test.ballots) {
+				t.Fatalf("expected winner %d of %d ballots, got %d of %d", test.winner, len(test.ballots), tally.Winner, tally.Ballots)
+			}
+		})
+	}
+}
+
+func TestBallotChoices(t *testing.T) {
+	options := []string{"a", "b", "c"}
+
+	tests := []struct {
+		name    string
+		voting  Voting
+		vote    Vote
+		choices []int
+		err     error
+	}{
+		{
+			name:    "yes",
+			voting:  NewVoting("voting"),
+			vote:    NewVote("", true),
+			choices: []int{0},
+		},
+		{
+			name:    "no",
+			voting:  NewVoting("voting"),
+			vote:    NewVote("", false),
+			choices: []int{1},
+		},
+		{
+			name:   "empty ballot",
+			voting: NewVotingWithOptions("voting", SingleChoiceVoting, options, 0),
+			vote:   NewVote("", true),
+			err:    ErrEmptyBallot,
+		},
+		{
+			name:   "two choices of single choice voting",
+			voting: NewVotingWithOptions("voting", SingleChoiceVoting, options, 0),
+			vote:   NewBallot("", []int{0, 1}),
+			err:    ErrTooManyChoices,
+		},
+		{
+			name:   "more choices than allowed",
+			voting: NewVotingWithOptions("voting", MultiChoiceVoting, options, 2),
+			vote:   NewBallot("", []int{0, 1, 2}),
+			err:    ErrTooManyChoices,
+		},
+		{
+			name:   "choice out of range",
+			voting: NewVotingWithOptions("voting", MultiChoiceVoting, options, 0),
+			vote:   NewBallot("", []int{3}),
+			err:    ErrInvalidChoice,
+		},
+		{
+			name:   "repeated choice",
+			voting: NewVotingWithOptions("voting", RankedChoiceVoting, options, 0),
+			vote:   NewBallot("", []int{1, 1}),
+			err:    ErrDuplicateChoice,
+		},
+		{
+			name:    "ranked choices keep order",
+			voting:  NewVotingWithOptions("voting", RankedChoiceVoting, options, 0),
+			vote:    NewBallot("", []int{2, 0, 1}),
+			choices: []int{2, 0, 1},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			choices, err := test.voting.BallotChoices(test.vote)
+			if !errors.Is(err, test.err) {
+				t.Fatalf("expected %v, got %v", test.err, err)
+			}
+			if !slices.Equal(choices, test.choices) {
+				t.Fatalf("expected choices %v, got %v", test.choices, choices)
+			}
+		})
+	}
+}
+
+func TestGetTally(t *testing.T) {
+	creator := NewRandomWallet()
+	chain := NewChain([]Block{GenesisBlock})
+
+	voting := newTestTransaction(t, creator, 1, VotingMethod, NewVoting("voting"))
+	pushTestBlock(t, &chain, []Transaction{voting})
+
+	if _, err := chain.GetTally("unknown"); !errors.Is(err, ErrVotingNotFound) {
+		t.Fatalf("expected %v, got %v", ErrVotingNotFound, err)
+	}
+
+	tests := []struct {
+		name   string
+		txs    func() []Transaction
+		counts []int
+		winner int
+		status VotingStatus
+	}{
+		{
+			name:   "no votes",
+			txs:    func() []Transaction { return nil },
+			counts: []int{0, 0},
+			winner: NoWinner,
+			status: OpenVoting,
+		},
+		{
+			name: "vote",
+			txs: func() []Transaction {
+				return []Transaction{newTestTransaction(t, NewRandomWallet(), 1, VoteMethod, NewVote(voting.Hash, true))}
+			},
+			counts: []int{1, 0},
+			winner: 0,
+			status: OpenVoting,
+		},
+		{
+			name: "votes of next block",
+			txs: func() []Transaction {
+				return []Transaction{
+					newTestTransaction(t, NewRandomWallet(), 1, VoteMethod, NewVote(voting.Hash, false)),
+					newTestTransaction(t, NewRandomWallet(), 1, VoteMethod, NewVote(voting.Hash, false)),
+				}
+			},
+			counts: []int{1, 2},
+			winner: 1,
+			status: OpenVoting,
+		},
+		{
+			name: "close",
+			txs: func() []Transaction {
+				return []Transaction{newTestTransaction(t, creator, 2, CloseMethod, NewClose(voting.Hash))}
+			},
+			counts: []int{1, 2},
+			winner: 1,
+			status: ClosedVoting,
+		},
+	}
+
+	// cases run in order on the same chain, so tally taken from index is
+	// checked to follow the tip
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if txs := test.txs(); len(txs) > 0 {
+				pushTestBlock(t, &chain, txs)
+			}
+
+			tally, err := chain.GetTally(voting.Hash)
+			if err != nil {
+				t.Fatalf("failed to get tally: %v", err)
+			}
+			if !slices.Equal(tally.Counts, test.counts) || tally.Winner != test.winner {
+				t.Fatalf("expected counts %v with winner %d, got %v with %d", test.counts, test.winner, tally.Counts, tally.Winner)
+			}
+			if tally.Status != test.status || tally.Final != (test.status == ClosedVoting) {
+				t.Fatalf("expected %s voting, got %s (final %v)", test.status, tally.Status, tally.Final)
+			}
+		})
+	}
+}
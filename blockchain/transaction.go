@@ -0,0 +1,252 @@
+package blockchain
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"time"
+)
+
+const MaxBlockTransactions = 1000
+
+type TransactionData struct {
+	// Network is genesis hash of network transaction is made for, so it
+	// can not be replayed on other networks
+	Network  string  `json:"network"`
+	From     Address `json:"from"`
+	Sequence uint64  `json:"sequence"`
+	Call     Call    `json:"call"`
+}
+
+func (d TransactionData) Hash() ([32]byte, error) {
+	return Hash(d)
+}
+
+// Transaction is call signed by voter, sequence is number of transaction
+// among all transactions of voter starting from 1.
+type Transaction struct {
+	TransactionData
+	Hash      string    `json:"hash"`
+	Signature Signature `json:"signature"`
+}
+
+func NewTransaction(signer Wallet, network string, sequence uint64, call Call) (Transaction, error) {
+	addr, err := signer.Address()
+	if err != nil {
+		return Transaction{}, fmt.Errorf("failed to get address from wallet: %v", err)
+	}
+
+	data := TransactionData{
+		Network:  network,
+		From:     addr,
+		Sequence: sequence,
+		Call:     call,
+	}
+
+	hash, err := data.Hash()
+	if err != nil {
+		return Transaction{}, fmt.Errorf("failed to hash transaction: %v", err)
+	}
+
+	signature, err := signer.Sign(hash[:])
+	if err != nil {
+		return Transaction{}, fmt.Errorf("failed to sign transaction: %v", err)
+	}
+
+	return Transaction{
+		TransactionData: data,
+		Hash:            hex.EncodeToString(hash[:]),
+		Signature:       signature,
+	}, nil
+}
+
+func (t Transaction) Verify() (bool, error) {
+	hash, err := t.TransactionData.Hash()
+	if err != nil {
+		return false, fmt.Errorf("failed to hash transaction: %v", err)
+	}
+
+	if t.Hash != hex.EncodeToString(hash[:]) {
+		return false, nil
+	}
+
+	ok, err := t.Signature.Verify(t.From, hash[:])
+	if err != nil {
+		return false, fmt.Errorf("failed to verify signature: %w", err)
+	}
+
+	return ok, nil
+}
+
+func TransactionsRoot(txs []Transaction) (string, error) {
+	if len(txs) == 0 {
+		return "", nil
+	}
+
+	leaves := make([][32]byte, 0, len(txs))
+	for _, tx := range txs {
+		hash, err := hex.DecodeString(tx.Hash)
+		if err != nil || len(hash) != 32 {
+			return "", fmt.Errorf("invalid transaction hash %s", tx.Hash)
+		}
+		leaves = append(leaves, [32]byte(hash))
+	}
+
+	root := MerkleRoot(leaves)
+	return hex.EncodeToString(root[:]), nil
+}
+
+// BlockCall is call included in block either as block data, signed by
+// block creator, or as transaction.
+type BlockCall struct {
+	Call
+	// ID is hash of transaction or hash of block for calls stored in
+	// block data
+	ID    string
+	From  Address
+	Block Block
+}
+
+func (b Block) Calls() []BlockCall {
+	calls := make([]BlockCall, 0, len(b.Transactions)+1)
+
+	if len(b.Data) > 0 {
+		var call Call
+		if err := json.Unmarshal(b.Data, &call); err == nil {
+			calls = append(calls, BlockCall{
+				Call:  call,
+				ID:    b.BlockHash,
+				From:  b.From,
+				Block: b,
+			})
+		}
+	}
+
+	for _, tx := range b.Transactions {
+		calls = append(calls, BlockCall{
+			Call:  tx.Call,
+			ID:    tx.Hash,
+			From:  tx.From,
+			Block: b,
+		})
+	}
+
+	return calls
+}
+
+// pendingBlock is unsealed block on top of chain, calls of which are
+// applied to index of chain until it is closed, so transactions are
+// validated as if they were in next block without rebuilding the index.
+type pendingBlock struct {
+	chain   Chain
+	idx     *index
+	block   Block
+	reverts []func()
+}
+
+// newPendingBlock applies calls of block to index of chain, pending block
+// must be closed before chain is changed.
+func (c Chain) newPendingBlock(block Block) *pendingBlock {
+	p := &pendingBlock{
+		chain:   c,
+		idx:     c.index(),
+		block:   block,
+		reverts: make([]func(), 0),
+	}
+	p.block.Transactions = slices.Clone(block.Transactions)
+	p.reverts = append(p.reverts, p.idx.applyCalls(p.block))
+
+	return p
+}
+
+// nextBlock returns unsealed block holding txs on top of chain.
+func (c Chain) nextBlock(txs []Transaction) Block {
+	lastBlock := c.GetLastBlock()
+
+	return Block{
+		SignBlockData: SignBlockData{
+			BlockData: BlockData{
+				PrevBlockHash: lastBlock.BlockHash,
+				Nonce:         lastBlock.Nonce + 1,
+				Timestamp:     time.Now().Unix(),
+			},
+		},
+		Transactions: txs,
+	}
+}
+
+func (p *pendingBlock) validate(tx Transaction) error {
+	return p.chain.ValidateTransaction(p.block, tx)
+}
+
+// add puts tx to the end of pending block.
+func (p *pendingBlock) add(tx Transaction) {
+	p.block.Transactions = append(p.block.Transactions, tx)
+
+	if tx.Call.Method == CloseMethod {
+		// closes of block are applied before its votes, so the whole block
+		// is applied again
+		p.close()
+		p.reverts = append(p.reverts, p.idx.applyCalls(p.block))
+		return
+	}
+
+	single := p.block
+	single.Data = nil
+	single.Transactions = []Transaction{tx}
+	p.reverts = append(p.reverts, p.idx.applyCalls(single))
+}
+
+// close reverts calls of pending block from index of chain.
+func (p *pendingBlock) close() {
+	for i := len(p.reverts) - 1; i >= 0; i-- {
+		p.reverts[i]()
+	}
+	p.reverts = p.reverts[:0]
+}
+
+// ValidatePendingTransaction checks that tx is valid in next block after
+// pending transactions.
+func (c Chain) ValidatePendingTransaction(pending []Transaction, tx Transaction) error {
+	p := c.newPendingBlock(c.nextBlock(pending))
+	defer p.close()
+
+	return p.validate(tx)
+}
+
+// SelectTransactions returns transactions which can be included in next
+// block in given order, and ones which are not valid anymore.
+func (c Chain) SelectTransactions(txs []Transaction) ([]Transaction, []Transaction) {
+	return c.selectTransactions(txs, MaxBlockTransactions)
+}
+
+// FilterTransactions returns transactions which are valid in given order
+// on top of chain, regardless of block size.
+func (c Chain) FilterTransactions(txs []Transaction) []Transaction {
+	valid, _ := c.selectTransactions(txs, len(txs))
+	return valid
+}
+
+func (c Chain) selectTransactions(txs []Transaction, limit int) ([]Transaction, []Transaction) {
+	valid := make([]Transaction, 0)
+	invalid := make([]Transaction, 0)
+
+	p := c.newPendingBlock(c.nextBlock(nil))
+	defer p.close()
+
+	for _, tx := range txs {
+		if len(valid) >= limit {
+			break
+		}
+
+		if err := p.validate(tx); err != nil {
+			invalid = append(invalid, tx)
+			continue
+		}
+		p.add(tx)
+		valid = append(valid, tx)
+	}
+
+	return valid, invalid
+}
@@ -0,0 +1,210 @@
+package blockchain
+
+import (
+	"encoding/json"
+	"errors"
+	"slices"
+	"testing"
+)
+
+// newTestTransaction signs call of method with payload by wallet.
+func newTestTransaction(t *testing.T, wallet Wallet, sequence uint64, method Method, payload any) Transaction {
+	t.Helper()
+
+	data, err := json.Marshal(payload)
+	if err != nil {
+		t.Fatalf("failed to marshal payload: %v", err)
+	}
+
+	tx, err := NewTransaction(wallet, GenesisBlock.BlockHash, sequence, Call{Method: method, Data: data})
+	if err != nil {
+		t.Fatalf("failed to create transaction: %v", err)
+	}
+
+	return tx
+}
+
+// pushTestBlock mines block holding txs on top of chain and pushes it.
+func pushTestBlock(t *testing.T, chain *Chain, txs []Transaction) Block {
+	t.Helper()
+
+	block, err := NewBlockWithTransactions(chain.GetLastBlock(), NewRandomWallet(), txs)
+	if err != nil {
+		t.Fatalf("failed to create block: %v", err)
+	}
+	block.Difficulty = chain.NextDifficulty()
+	block.StateRoot = chain.NextStateRoot(block)
+	mineTestBlock(t, &block)
+	signTestBlock(t, &block)
+
+	if _, err := chain.PushBlock(block); err != nil {
+		t.Fatalf("failed to push block: %v", err)
+	}
+
+	return block
+}
+
+func TestSelectTransactions(t *testing.T) {
+	creator := NewRandomWallet()
+	voter := NewRandomWallet()
+	voterAddress, err := voter.Address()
+	if err != nil {
+		t.Fatalf("failed to get address: %v", err)
+	}
+
+	chain := NewChain([]Block{GenesisBlock})
+	voting := newTestTransaction(t, creator, 1, VotingMethod, NewVoting("voting"))
+	forbid := newTestTransaction(t, creator, 2, VotingMethod, NewVoting("forbid").WithDuplicatePolicy(ForbidDuplicates))
+	pushTestBlock(t, &chain, []Transaction{voting, forbid})
+
+	tests := []struct {
+		name string
+		txs  func() []Transaction
+		// valid are indexes of transactions selected for block
+		valid []int
+	}{
+		{
+			name: "votes of voter in order",
+			txs: func() []Transaction {
+				return []Transaction{
+					newTestTransaction(t, voter, 1, VoteMethod, Vote{Voting: voting.Hash, Value: true}),
+					newTestTransaction(t, voter, 2, VoteMethod, Vote{Voting: forbid.Hash, Value: true}),
+				}
+			},
+			valid: []int{0, 1},
+		},
+		{
+			name: "sequence gap",
+			txs: func() []Transaction {
+				return []Transaction{
+					newTestTransaction(t, voter, 2, VoteMethod, Vote{Voting: voting.Hash, Value: true}),
+					newTestTransaction(t, voter, 1, VoteMethod, Vote{Voting: voting.Hash, Value: true}),
+				}
+			},
+			valid: []int{1},
+		},
+		{
+			name: "vote for voting of the same block",
+			txs: func() []Transaction {
+				created := newTestTransaction(t, creator, 3, VotingMethod, NewVoting("new"))
+				return []Transaction{
+					created,
+					newTestTransaction(t, voter, 1, VoteMethod, Vote{Voting: created.Hash, Value: true}),
+				}
+			},
+			valid: []int{0},
+		},
+		{
+			name: "vote after close",
+			txs: func() []Transaction {
+				return []Transaction{
+					newTestTransaction(t, creator, 3, CloseMethod, NewClose(voting.Hash)),
+					newTestTransaction(t, voter, 1, VoteMethod, Vote{Voting: voting.Hash, Value: true}),
+				}
+			},
+			valid: []int{0},
+		},
+		{
+			name: "close after vote",
+			txs: func() []Transaction {
+				return []Transaction{
+					newTestTransaction(t, voter, 1, VoteMethod, Vote{Voting: voting.Hash, Value: true}),
+					newTestTransaction(t, creator, 3, CloseMethod, NewClose(voting.Hash)),
+				}
+			},
+			valid: []int{0, 1},
+		},
+		{
+			name: "close by other address",
+			txs: func() []Transaction {
+				return []Transaction{
+					newTestTransaction(t, voter, 1, CloseMethod, NewClose(voting.Hash)),
+				}
+			},
+			valid: []int{},
+		},
+		{
+			name: "duplicate vote is forbidden",
+			txs: func() []Transaction {
+				return []Transaction{
+					newTestTransaction(t, voter, 1, VoteMethod, Vote{Voting: forbid.Hash, Value: true}),
+					newTestTransaction(t, voter, 2, VoteMethod, Vote{Voting: forbid.Hash, Value: false}),
+				}
+			},
+			valid: []int{0},
+		},
+		{
+			name: "duplicate vote replaces ballot",
+			txs: func() []Transaction {
+				return []Transaction{
+					newTestTransaction(t, voter, 1, VoteMethod, Vote{Voting: voting.Hash, Value: true}),
+					newTestTransaction(t, voter, 2, VoteMethod, Vote{Voting: voting.Hash, Value: false}),
+				}
+			},
+			valid: []int{0, 1},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			state := chain.idx.state
+			root := chain.StateRoot()
+
+			txs := test.txs()
+			valid, invalid := chain.SelectTransactions(txs)
+
+			expected := make([]Transaction, 0)
+			for _, i := range test.valid {
+				expected = append(expected, txs[i])
+			}
+			if !slices.EqualFunc(valid, expected, func(a, b Transaction) bool { return a.Hash == b.Hash }) {
+				t.Fatalf("expected %d valid transactions, got %d", len(expected), len(valid))
+			}
+			if len(valid)+len(invalid) != len(txs) {
+				t.Fatalf("expected %d invalid transactions, got %d", len(txs)-len(valid), len(invalid))
+			}
+
+			// selected transactions must be accepted one by one and as block
+			for i, tx := range valid {
+				if err := chain.ValidatePendingTransaction(valid[:i], tx); err != nil {
+					t.Fatalf("selected transaction %d is not valid: %v", i, err)
+				}
+			}
+			block, err := NewBlockWithTransactions(chain.GetLastBlock(), NewRandomWallet(), valid)
+			if err != nil {
+				t.Fatalf("failed to create block: %v", err)
+			}
+			if err := chain.ValidateBlockCalls(block); err != nil {
+				t.Fatalf("selected transactions are not valid in block: %v", err)
+			}
+			block.Transactions = txs
+			if err := chain.ValidateBlockCalls(block); (err == nil) != (len(invalid) == 0) {
+				t.Fatalf("block of all transactions is validated with %v, while %d are invalid", err, len(invalid))
+			}
+
+			if chain.idx.state != state {
+				t.Fatalf("index was rebuilt")
+			}
+			if got := chain.StateRoot(); got != root {
+				t.Fatalf("pending transactions changed state root: %s != %s", got, root)
+			}
+			if got := chain.GetSequence(voterAddress); got != 0 {
+				t.Fatalf("pending transactions changed sequence to %d", got)
+			}
+		})
+	}
+}
+
+func TestValidateBlockCallsWrapsTransactionError(t *testing.T) {
+	chain := NewChain([]Block{GenesisBlock})
+	tx := newTestTransaction(t, NewRandomWallet(), 1, VoteMethod, Vote{Voting: "unknown"})
+
+	block, err := NewBlockWithTransactions(chain.GetLastBlock(), NewRandomWallet(), []Transaction{tx})
+	if err != nil {
+		t.Fatalf("failed to create block: %v", err)
+	}
+
+	if err := chain.ValidateBlockCalls(block); !errors.Is(err, ErrUnknownVoting) {
+		t.Fatalf("expected %v, got %v", ErrUnknownVoting, err)
+	}
+}
@@ -4,15 +4,16 @@ import (
 	"crypto/sha256"
 	"encoding/json"
 	"fmt"
+	"math/bits"
 )
 
+// GetDifficulty returns number of leading zero bits of hash.
 func GetDifficulty(data [32]byte) uint64 {
 	var difficulty uint64 = 0
 
 	for i := 0; i < len(data); i++ {
-		if data[i] == 0 {
-			difficulty++
-		} else {
+		difficulty += uint64(bits.LeadingZeros8(data[i]))
+		if data[i] != 0 {
 			break
 		}
 	}
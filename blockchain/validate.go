@@ -0,0 +1,217 @@
+package blockchain
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	ErrMalformedCall     = errors.New("malformed call")
+	ErrMalformedCallData = errors.New("malformed call data")
+	ErrUnknownMethod     = errors.New("unknown method")
+	ErrEmptyTitle        = errors.New("voting title is empty")
+	ErrInvalidVoting     = errors.New("invalid voting")
+	ErrUnknownVoting     = errors.New("unknown voting")
+	ErrUnknownRegistry   = errors.New("unknown registry voting")
+	ErrVotingNotStarted  = errors.New("voting has not started")
+	ErrVotingClosed      = errors.New("voting is closed")
+	ErrInvalidBallot     = errors.New("invalid ballot")
+	ErrNotVotingCreator  = errors.New("only creator may close voting")
+	ErrDuplicateVote     = errors.New("address has already voted")
+	ErrIncorrectSequence = errors.New("incorrect transaction sequence")
+	ErrOtherNetwork      = errors.New("transaction is made for other network")
+)
+
+// DuplicateVoteError is returned when voting forbids duplicates and
+// voter has already cast counted vote.
+type DuplicateVoteError struct {
+	Voting string
+	Voter  Address
+}
+
+func (e DuplicateVoteError) Error() string {
+	return fmt.Sprintf("%v: %s in voting %s", ErrDuplicateVote, e.Voter, e.Voting)
+}
+
+func (e DuplicateVoteError) Unwrap() error {
+	return ErrDuplicateVote
+}
+
+// ValidateBlockCalls checks call stored in block data and every
+// transaction of block on top of chain. Transactions are validated in
+// order, so they may depend on previous transactions of the same block.
+func (c Chain) ValidateBlockCalls(block Block) error {
+	partial := block
+	partial.Transactions = nil
+
+	if len(block.Data) > 0 {
+		var call Call
+		if err := json.Unmarshal(block.Data, &call); err != nil {
+			return fmt.Errorf("%w: %v", ErrMalformedCall, err)
+		}
+
+		if err := c.ValidateCall(BlockCall{
+			Call:  call,
+			ID:    block.BlockHash,
+			From:  block.From,
+			Block: block,
+		}); err != nil {
+			return err
+		}
+	}
+
+	p := c.newPendingBlock(partial)
+	defer p.close()
+
+	for _, tx := range block.Transactions {
+		if err := p.validate(tx); err != nil {
+			return fmt.Errorf("invalid transaction %s: %w", tx.Hash, err)
+		}
+		p.add(tx)
+	}
+
+	return nil
+}
+
+// ValidateTransaction checks tx included in block, which must be the last
+// block of chain.
+func (c Chain) ValidateTransaction(block Block, tx Transaction) error {
+	ok, err := tx.Verify()
+	if err != nil {
+		return fmt.Errorf("failed to verify transaction: %v", err)
+	}
+	if !ok {
+		return ErrIncorrectSignature
+	}
+
+	if network := c.GenesisBlock().BlockHash; tx.Network != network {
+		return fmt.Errorf("%w %s", ErrOtherNetwork, tx.Network)
+	}
+
+	if tx.Sequence != c.GetSequence(tx.From)+1 {
+		return fmt.Errorf("%w: expected %d, got %d", ErrIncorrectSequence, c.GetSequence(tx.From)+1, tx.Sequence)
+	}
+
+	return c.ValidateCall(BlockCall{
+		Call:  tx.Call,
+		ID:    tx.Hash,
+		From:  tx.From,
+		Block: block,
+	})
+}
+
+// ValidateCall checks that call makes sense on top of chain: payload is
+// well formed and refers to existing votings.
+func (c Chain) ValidateCall(call BlockCall) error {
+	switch call.Method {
+	case VotingMethod:
+		var voting Voting
+		if err := json.Unmarshal(call.Data, &voting); err != nil {
+			return fmt.Errorf("%w: %v", ErrMalformedCallData, err)
+		}
+		return c.validateVoting(call, voting)
+	case VoteMethod:
+		var vote Vote
+		if err := json.Unmarshal(call.Data, &vote); err != nil {
+			return fmt.Errorf("%w: %v", ErrMalformedCallData, err)
+		}
+		return c.validateVote(call, vote)
+	case CloseMethod:
+		var closeCall Close
+		if err := json.Unmarshal(call.Data, &closeCall); err != nil {
+			return fmt.Errorf("%w: %v", ErrMalformedCallData, err)
+		}
+		return c.validateClose(call, closeCall)
+	default:
+		return fmt.Errorf("%w: %s", ErrUnknownMethod, call.Method)
+	}
+}
+
+func (c Chain) validateVoting(call BlockCall, voting Voting) error {
+	if !c.Genesis().IsAdmin(call.From) {
+		return ErrNotAdmin
+	}
+
+	if strings.TrimSpace(voting.Title) == "" {
+		return ErrEmptyTitle
+	}
+
+	if err := voting.Validate(); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidVoting, err)
+	}
+
+	if voting.Electorate != nil && voting.Electorate.Registry != "" {
+		if _, ok := c.GetVotingState(voting.Electorate.Registry); !ok {
+			return ErrUnknownRegistry
+		}
+	}
+	if voting.Electorate != nil && voting.Electorate.Genesis != "" {
+		if _, ok := c.Genesis().Electorates[voting.Electorate.Genesis]; !ok {
+			return fmt.Errorf("%w: %s", ErrUnknownElectorate, voting.Electorate.Genesis)
+		}
+	}
+
+	return nil
+}
+
+func (c Chain) validateVote(call BlockCall, vote Vote) error {
+	// state is used instead of GetVoting, as voting may be created in
+	// pending block which is not in chain
+	voting, ok := c.GetVotingState(vote.Voting)
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrUnknownVoting, vote.Voting)
+	}
+
+	// votes are counted only from blocks after voting block
+	if call.Block.Nonce <= voting.Nonce {
+		return ErrVotingNotStarted
+	}
+
+	if voting.Closed {
+		return ErrVotingClosed
+	}
+	if !voting.Voting.InWindow(call.Block) {
+		if voting.Voting.Ended(call.Block) {
+			return ErrVotingClosed
+		}
+		return ErrVotingNotStarted
+	}
+
+	if _, err := voting.Voting.BallotChoices(vote); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidBallot, err)
+	}
+
+	if !c.IsEligible(voting, call.From, vote) {
+		return ErrIneligibleVoter
+	}
+
+	if voting.Voting.GetDuplicatePolicy() == ForbidDuplicates {
+		if _, ok := voting.Ballots[call.From]; ok {
+			return DuplicateVoteError{
+				Voting: vote.Voting,
+				Voter:  call.From,
+			}
+		}
+	}
+
+	return nil
+}
+
+func (c Chain) validateClose(call BlockCall, closeCall Close) error {
+	voting, ok := c.GetVotingState(closeCall.Voting)
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrUnknownVoting, closeCall.Voting)
+	}
+
+	if voting.Creator != call.From {
+		return ErrNotVotingCreator
+	}
+
+	if voting.Closed {
+		return ErrVotingClosed
+	}
+
+	return nil
+}
@@ -0,0 +1,108 @@
+package blockchain
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestDuplicateVotePolicy(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy DuplicatePolicy
+		// sameBlock puts both votes in one block
+		sameBlock bool
+		// value is value of counted ballot
+		value bool
+		err   error
+	}{
+		{
+			name:   "first vote wins by default",
+			policy: "",
+			value:  true,
+		},
+		{
+			name:   "first vote wins",
+			policy: FirstVoteWins,
+			value:  true,
+		},
+		{
+			name:      "first vote wins in the same block",
+			policy:    FirstVoteWins,
+			sameBlock: true,
+			value:     true,
+		},
+		{
+			name:   "last vote wins",
+			policy: LastVoteWins,
+			value:  false,
+		},
+		{
+			name:      "last vote wins in the same block",
+			policy:    LastVoteWins,
+			sameBlock: true,
+			value:     false,
+		},
+		{
+			name:   "duplicates are forbidden",
+			policy: ForbidDuplicates,
+			value:  true,
+			err:    ErrDuplicateVote,
+		},
+		{
+			name:      "duplicates are forbidden in the same block",
+			policy:    ForbidDuplicates,
+			sameBlock: true,
+			err:       ErrDuplicateVote,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			voter := NewRandomWallet()
+			voterAddress, err := voter.Address()
+			if err != nil {
+				t.Fatalf("failed to get address: %v", err)
+			}
+
+			chain := NewChain([]Block{GenesisBlock})
+			voting := newTestTransaction(t, NewRandomWallet(), 1, VotingMethod, NewVoting("voting").WithDuplicatePolicy(test.policy))
+			pushTestBlock(t, &chain, []Transaction{voting})
+
+			first := newTestTransaction(t, voter, 1, VoteMethod, NewVote(voting.Hash, true))
+			second := newTestTransaction(t, voter, 2, VoteMethod, NewVote(voting.Hash, false))
+			txs := []Transaction{first, second}
+			if !test.sameBlock {
+				pushTestBlock(t, &chain, []Transaction{first})
+				txs = txs[1:]
+			}
+
+			block, err := NewBlockWithTransactions(chain.GetLastBlock(), NewRandomWallet(), txs)
+			if err != nil {
+				t.Fatalf("failed to create block: %v", err)
+			}
+			err = chain.ValidateBlockCalls(block)
+			if !errors.Is(err, test.err) {
+				t.Fatalf("expected %v, got %v", test.err, err)
+			}
+			if err != nil {
+				var duplicate DuplicateVoteError
+				if !errors.As(err, &duplicate) || duplicate.Voter != voterAddress || duplicate.Voting != voting.Hash {
+					t.Fatalf("expected duplicate vote of %s, got %v", voterAddress, err)
+				}
+				if ballot, ok := chain.GetVotes(voting.Hash)[voterAddress]; ok != !test.sameBlock || ballot.Value != test.value {
+					t.Fatalf("ballot is changed by rejected vote")
+				}
+				return
+			}
+
+			pushTestBlock(t, &chain, txs)
+			ballots := chain.GetVotes(voting.Hash)
+			if len(ballots) != 1 || ballots[voterAddress].Value != test.value {
+				t.Fatalf("expected single ballot with value %v, got %+v", test.value, ballots)
+			}
+			if chain.GetSequence(voterAddress) != 2 {
+				t.Fatalf("ignored duplicate vote did not use sequence")
+			}
+		})
+	}
+}
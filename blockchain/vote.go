@@ -1,16 +1,76 @@
 package blockchain
 
-import "encoding/json"
+import (
+	"encoding/json"
+	"errors"
+)
 
 type Method string
 
 const (
 	VoteMethod   Method = "vote"
 	VotingMethod Method = "voting"
+	CloseMethod  Method = "close"
+)
+
+type VotingKind string
+
+const (
+	// SingleChoiceVoting allows exactly one option per ballot. Votings
+	// without explicit kind are single choice.
+	SingleChoiceVoting VotingKind = "single"
+	// MultiChoiceVoting allows to approve up to MaxChoices options.
+	MultiChoiceVoting VotingKind = "multi"
+	// RankedChoiceVoting expects options ordered by preference and is
+	// tallied with instant-runoff.
+	RankedChoiceVoting VotingKind = "ranked"
+)
+
+// DuplicatePolicy decides what happens when address votes more than once
+// in the same voting.
+type DuplicatePolicy string
+
+const (
+	// FirstVoteWins counts first vote and ignores later ones. Votings
+	// without explicit policy use it.
+	FirstVoteWins DuplicatePolicy = "first"
+	// LastVoteWins lets voters change their mind until voting ends.
+	LastVoteWins DuplicatePolicy = "last"
+	// ForbidDuplicates rejects blocks with repeated votes.
+	ForbidDuplicates DuplicatePolicy = "forbid"
+)
+
+var DefaultOptions = []string{"yes", "no"}
+
+var (
+	ErrUnknownVotingKind = errors.New("unknown voting kind")
+	ErrNotEnoughOptions  = errors.New("voting needs at least two options")
+	ErrDuplicateOption   = errors.New("duplicate option")
+	ErrEmptyBallot       = errors.New("ballot has no choices")
+	ErrInvalidChoice     = errors.New("choice is out of options range")
+	ErrDuplicateChoice   = errors.New("choice is repeated")
+	ErrTooManyChoices    = errors.New("too many choices")
+	ErrInvalidWindow     = errors.New("voting ends before it starts")
+	ErrUnknownPolicy     = errors.New("unknown duplicate policy")
 )
 
 type Voting struct {
-	Title string `json:"title"`
+	Title      string     `json:"title"`
+	Kind       VotingKind `json:"kind,omitempty"`
+	Options    []string   `json:"options,omitempty"`
+	MaxChoices int        `json:"maxChoices,omitempty"`
+
+	// Votes are accepted from blocks with nonce in [StartHeight, EndHeight]
+	// and timestamp in [StartTime, EndTime), zero value disables the bound
+	StartHeight uint64 `json:"startHeight,omitempty"`
+	EndHeight   uint64 `json:"endHeight,omitempty"`
+	StartTime   int64  `json:"startTime,omitempty"`
+	EndTime     int64  `json:"endTime,omitempty"`
+
+	// Electorate limits who may vote, everyone may vote when it is nil
+	Electorate *Electorate `json:"electorate,omitempty"`
+
+	Duplicates DuplicatePolicy `json:"duplicates,omitempty"`
 }
 
 func NewVoting(title string) Voting {
@@ -19,20 +79,210 @@ func NewVoting(title string) Voting {
 	}
 }
 
+func NewVotingWithOptions(title string, kind VotingKind, options []string, maxChoices int) Voting {
+	return Voting{
+		Title:      title,
+		Kind:       kind,
+		Options:    options,
+		MaxChoices: maxChoices,
+	}
+}
+
+func (v Voting) WithHeightWindow(start uint64, end uint64) Voting {
+	v.StartHeight = start
+	v.EndHeight = end
+	return v
+}
+
+func (v Voting) WithTimeWindow(start int64, end int64) Voting {
+	v.StartTime = start
+	v.EndTime = end
+	return v
+}
+
+func (v Voting) WithElectorate(electorate *Electorate) Voting {
+	v.Electorate = electorate
+	return v
+}
+
+func (v Voting) WithDuplicatePolicy(policy DuplicatePolicy) Voting {
+	v.Duplicates = policy
+	return v
+}
+
 func (v Voting) Data() []byte {
 	data, _ := json.Marshal(v)
 	return data
 }
 
+func (v Voting) GetKind() VotingKind {
+	if v.Kind == "" {
+		return SingleChoiceVoting
+	}
+
+	return v.Kind
+}
+
+// AllOptions returns declared options or yes/no for votings created
+// without them.
+func (v Voting) AllOptions() []string {
+	if len(v.Options) == 0 {
+		return DefaultOptions
+	}
+
+	return v.Options
+}
+
+func (v Voting) GetDuplicatePolicy() DuplicatePolicy {
+	if v.Duplicates == "" {
+		return FirstVoteWins
+	}
+
+	return v.Duplicates
+}
+
+func (v Voting) GetMaxChoices() int {
+	switch v.GetKind() {
+	case SingleChoiceVoting:
+		return 1
+	case MultiChoiceVoting:
+		if v.MaxChoices > 0 && v.MaxChoices < len(v.AllOptions()) {
+			return v.MaxChoices
+		}
+	}
+
+	return len(v.AllOptions())
+}
+
+func (v Voting) Validate() error {
+	switch v.GetKind() {
+	case SingleChoiceVoting, MultiChoiceVoting, RankedChoiceVoting:
+	default:
+		return ErrUnknownVotingKind
+	}
+
+	options := v.AllOptions()
+	if len(options) < 2 {
+		return ErrNotEnoughOptions
+	}
+
+	seen := make(map[string]bool)
+	for _, option := range options {
+		if seen[option] {
+			return ErrDuplicateOption
+		}
+		seen[option] = true
+	}
+
+	if v.EndHeight != 0 && v.EndHeight < v.StartHeight {
+		return ErrInvalidWindow
+	}
+	if v.EndTime != 0 && v.EndTime <= v.StartTime {
+		return ErrInvalidWindow
+	}
+
+	switch v.GetDuplicatePolicy() {
+	case FirstVoteWins, LastVoteWins, ForbidDuplicates:
+	default:
+		return ErrUnknownPolicy
+	}
+
+	if v.Electorate != nil {
+		if err := v.Electorate.Validate(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// InWindow reports whether block lies inside voting window.
+func (v Voting) InWindow(block Block) bool {
+	if v.StartHeight != 0 && block.Nonce < v.StartHeight {
+		return false
+	}
+	if v.EndHeight != 0 && block.Nonce > v.EndHeight {
+		return false
+	}
+	if v.StartTime != 0 && block.Timestamp < v.StartTime {
+		return false
+	}
+	if v.EndTime != 0 && block.Timestamp >= v.EndTime {
+		return false
+	}
+
+	return true
+}
+
+// Ended reports whether no block after lastBlock can be inside window.
+func (v Voting) Ended(lastBlock Block) bool {
+	if v.EndHeight != 0 && lastBlock.Nonce >= v.EndHeight {
+		return true
+	}
+	if v.EndTime != 0 && lastBlock.Timestamp >= v.EndTime {
+		return true
+	}
+
+	return false
+}
+
+// BallotChoices validates vote against options of voting and returns
+// indexes of chosen options. For ranked votings choices are ordered by
+// preference.
+func (v Voting) BallotChoices(vote Vote) ([]int, error) {
+	choices := vote.Choices
+	if len(choices) == 0 && len(v.Options) == 0 {
+		if vote.Value {
+			choices = []int{0}
+		} else {
+			choices = []int{1}
+		}
+	}
+
+	if len(choices) == 0 {
+		return nil, ErrEmptyBallot
+	}
+	if len(choices) > v.GetMaxChoices() {
+		return nil, ErrTooManyChoices
+	}
+
+	options := v.AllOptions()
+	seen := make(map[int]bool)
+	for _, choice := range choices {
+		if choice < 0 || choice >= len(options) {
+			return nil, ErrInvalidChoice
+		}
+		if seen[choice] {
+			return nil, ErrDuplicateChoice
+		}
+		seen[choice] = true
+	}
+
+	return choices, nil
+}
+
 type Vote struct {
-	BlockHash string `json:"blockHash"`
-	Value     bool   `json:"value"`
+	// Voting is id of voting, json name is kept from times when votings
+	// were identified by block hash only
+	Voting  string `json:"blockHash"`
+	Value   bool   `json:"value"`
+	Choices []int  `json:"choices,omitempty"`
+	// Proof is merkle proof of voter address for votings with electorate
+	// given by merkle root
+	Proof []string `json:"proof,omitempty"`
 }
 
-func NewVote(blockHash string, value bool) Vote {
+func NewVote(voting string, value bool) Vote {
 	return Vote{
-		BlockHash: blockHash,
-		Value:     value,
+		Voting: voting,
+		Value:  value,
+	}
+}
+
+func NewBallot(voting string, choices []int) Vote {
+	return Vote{
+		Voting:  voting,
+		Choices: choices,
 	}
 }
 
@@ -41,7 +291,29 @@ type VoteData struct {
 	Data   []byte `json:"data"`
 }
 
+func (v Vote) WithProof(proof []string) Vote {
+	v.Proof = proof
+	return v
+}
+
 func (v Vote) Data() []byte {
 	data, _ := json.Marshal(v)
 	return data
 }
+
+// Close ends voting before its window is over, only creator of voting
+// may close it.
+type Close struct {
+	Voting string `json:"blockHash"`
+}
+
+func NewClose(voting string) Close {
+	return Close{
+		Voting: voting,
+	}
+}
+
+func (c Close) Data() []byte {
+	data, _ := json.Marshal(c)
+	return data
+}
@@ -9,25 +9,25 @@ import (
 	"github.com/kotsmile/go-vote/p2p"
 )
 
-const MainNodeAddr = ":3001"
-
-// TODO: if amount of bad nodes will be more that 50% it is possible to cancel blocks
-// add ability for block finilization after creation to be sure that history is correct
+const (
+	MainNodeAddr    = ":3001"
+	MainNodeApiAddr = ":8001"
+)
 
 func connectionAndBroadcasting() {
 	fmt.Println("starting main node")
-	mainNode := node.NewNode("main.json", p2p.NewTcpTransport(MainNodeAddr), blockchain.NewRandomWallet())
+	mainNode := mustNewNode("main.json", p2p.NewTcpTransport(MainNodeAddr), blockchain.NewRandomWallet())
 	go mainNode.Start(true)
 	time.Sleep(time.Second * 1)
 
 	fmt.Println("starting user node 1")
 	wallet := blockchain.NewWalletFromString("8ed1d4ab8975e20a666f42783be40a345f1acffbf9660db9bd93a87883f4ff6c")
-	node1 := node.NewNode("node1.json", p2p.NewTcpTransport(":3002"), wallet)
+	node1 := mustNewNode("node1.json", p2p.NewTcpTransport(":3002"), wallet)
 	go node1.Start(false)
 	time.Sleep(time.Second * 1)
 
 	fmt.Println("starting user node 2")
-	node2 := node.NewNode("node2.json", p2p.NewTcpTransport(":3003"), wallet)
+	node2 := mustNewNode("node2.json", p2p.NewTcpTransport(":3003"), wallet)
 	go node2.Start(false)
 	time.Sleep(time.Second * 1)
 
@@ -50,6 +50,14 @@ func connectionAndBroadcasting() {
 	}
 }
 
+func mustNewNode(filepath string, transport p2p.Transport, signer blockchain.Wallet) *node.Node {
+	n, err := node.NewNode(filepath, transport, signer)
+	if err != nil {
+		panic(err)
+	}
+	return n
+}
+
 var (
 	MainNodeWallet = blockchain.NewWalletFromString("8df93ef0a4f3200125d8d27ab1c0bd0dde92cb11774b1665b8463aa462477294")
 	Node1Wallet    = blockchain.NewWalletFromString("7e6b66ecc028718f1ddecc24c2146ed3f3b625edd8deea5c4c0f59aa08e8a6dd")
@@ -67,17 +75,18 @@ func main() {
 	fmt.Printf("node2: %s\n", node2Addr[:10])
 
 	fmt.Println("starting main node")
-	mainNode := node.NewNode("main.json", p2p.NewTcpTransport(MainNodeAddr), MainNodeWallet).WithName("MainNode")
+	mainNode := mustNewNode("main.json", p2p.NewTcpTransport(MainNodeAddr), MainNodeWallet).WithName("MainNode").WithMining(true)
 	go mainNode.Start(true)
+	go mainNode.ServeApi(MainNodeApiAddr)
 	time.Sleep(time.Second * 1)
 
 	fmt.Println("starting user node 1")
-	node1 := node.NewNode("node1.json", p2p.NewTcpTransport(":3002"), Node1Wallet).WithName("Node1")
+	node1 := mustNewNode("node1.json", p2p.NewTcpTransport(":3002"), Node1Wallet).WithName("Node1")
 	go node1.Start(false)
 	time.Sleep(time.Second * 1)
 
 	fmt.Println("starting user node 2")
-	node2 := node.NewNode("node2.json", p2p.NewTcpTransport(":3003"), Node2Wallet).WithName("Node2")
+	node2 := mustNewNode("node2.json", p2p.NewTcpTransport(":3003"), Node2Wallet).WithName("Node2")
 	go node2.Start(false)
 
 	time.Sleep(5 * time.Second)
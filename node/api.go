@@ -0,0 +1,387 @@
+package node
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/kotsmile/go-vote/blockchain"
+)
+
+type CreateVotingRequest struct {
+	Title      string                `json:"title"`
+	Kind       blockchain.VotingKind `json:"kind"`
+	Options    []string              `json:"options"`
+	MaxChoices int                   `json:"maxChoices"`
+
+	StartHeight uint64 `json:"startHeight"`
+	EndHeight   uint64 `json:"endHeight"`
+	StartTime   int64  `json:"startTime"`
+	EndTime     int64  `json:"endTime"`
+
+	Electorate *blockchain.Electorate     `json:"electorate"`
+	Duplicates blockchain.DuplicatePolicy `json:"duplicates"`
+}
+
+type CastVoteRequest struct {
+	Voting  string   `json:"voting"`
+	Value   bool     `json:"value"`
+	Choices []int    `json:"choices"`
+	Proof   []string `json:"proof"`
+}
+
+type SendResponse struct {
+	// ID is hash of transaction, for votings it is id of voting
+	ID string `json:"id"`
+}
+
+type VotingResponse struct {
+	ID         string                `json:"id"`
+	Nonce      uint64                `json:"nonce"`
+	Creator    blockchain.Address    `json:"creator"`
+	Title      string                `json:"title"`
+	Kind       blockchain.VotingKind `json:"kind"`
+	Options    []string              `json:"options"`
+	MaxChoices int                   `json:"maxChoices"`
+
+	StartHeight uint64                  `json:"startHeight"`
+	EndHeight   uint64                  `json:"endHeight"`
+	StartTime   int64                   `json:"startTime"`
+	EndTime     int64                   `json:"endTime"`
+	Status      blockchain.VotingStatus `json:"status"`
+
+	Electorate *blockchain.Electorate     `json:"electorate,omitempty"`
+	Duplicates blockchain.DuplicatePolicy `json:"duplicates"`
+}
+
+type ResultsResponse struct {
+	Voting VotingResponse   `json:"voting"`
+	Tally  blockchain.Tally `json:"tally"`
+}
+
+type StatusResponse struct {
+	Name          string             `json:"name"`
+	ChainID       string             `json:"chainId"`
+	GenesisHash   string             `json:"genesisHash"`
+	Address       blockchain.Address `json:"address"`
+	ListenAddr    string             `json:"listenAddr"`
+	Height        uint64             `json:"height"`
+	Finalized     uint64             `json:"finalized"`
+	LastBlockHash string             `json:"lastBlockHash"`
+	StateRoot     string             `json:"stateRoot"`
+	Votings       int                `json:"votings"`
+	Pending       int                `json:"pending"`
+	Peers         int                `json:"peers"`
+	HashRate      uint64             `json:"hashRate"`
+	Consensus     string             `json:"consensus"`
+}
+
+type ErrorResponse struct {
+	Error string `json:"error"`
+}
+
+func (n *Node) ServeApi(addr string) error {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /votings", n.handleCreateVoting)
+	mux.HandleFunc("GET /votings", n.handleListVotings)
+	mux.HandleFunc("GET /votings/{id}", n.handleVoting)
+	mux.HandleFunc("GET /votings/{id}/results", n.handleResults)
+	mux.HandleFunc("POST /votings/{id}/close", n.handleCloseVoting)
+	mux.HandleFunc("POST /votes", n.handleCastVote)
+	mux.HandleFunc("GET /chain", n.handleChain)
+	mux.HandleFunc("GET /blocks/{nonce}", n.handleBlockByNonce)
+	mux.HandleFunc("GET /blocks/hash/{hash}", n.handleBlockByHash)
+	mux.HandleFunc("GET /addresses/{address}/blocks", n.handleBlocksByAddress)
+	mux.HandleFunc("GET /peers", n.handlePeers)
+	mux.HandleFunc("GET /status", n.handleStatus)
+
+	server := &http.Server{
+		Addr:    addr,
+		Handler: mux,
+	}
+
+	// server is set under lock, so Stop either closes it or api is not
+	// started at all
+	n.apiLock.Lock()
+	select {
+	case <-n.quitCh:
+		n.apiLock.Unlock()
+		return nil
+	default:
+	}
+	n.apiServer = server
+	n.apiLock.Unlock()
+
+	n.Log(fmt.Sprintf("serving api on %s", addr))
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("failed to serve api on %s: %v", addr, err)
+	}
+
+	return nil
+}
+
+func (n *Node) handleCreateVoting(w http.ResponseWriter, r *http.Request) {
+	var req CreateVotingRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("failed to deserialize request: %v", err))
+		return
+	}
+
+	voting := blockchain.NewVotingWithOptions(req.Title, req.Kind, req.Options, req.MaxChoices).
+		WithHeightWindow(req.StartHeight, req.EndHeight).
+		WithTimeWindow(req.StartTime, req.EndTime).
+		WithElectorate(req.Electorate).
+		WithDuplicatePolicy(req.Duplicates)
+
+	id, err := n.SendVoting(voting)
+	if err != nil {
+		writeError(w, errorStatus(err), err)
+		return
+	}
+
+	writeJson(w, http.StatusOK, SendResponse{ID: id})
+}
+
+func (n *Node) handleCastVote(w http.ResponseWriter, r *http.Request) {
+	var req CastVoteRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("failed to deserialize request: %v", err))
+		return
+	}
+
+	vote := blockchain.NewVote(req.Voting, req.Value)
+	if len(req.Choices) > 0 {
+		vote = blockchain.NewBallot(req.Voting, req.Choices)
+	}
+	vote = vote.WithProof(req.Proof)
+
+	id, err := n.SendVote(vote)
+	if err != nil {
+		writeError(w, errorStatus(err), err)
+		return
+	}
+
+	writeJson(w, http.StatusOK, SendResponse{ID: id})
+}
+
+func (n *Node) handleListVotings(w http.ResponseWriter, r *http.Request) {
+	n.chainLock.Lock()
+	votings := n.Chain.GetVotings()
+	n.chainLock.Unlock()
+
+	res := make([]VotingResponse, 0, len(votings))
+	for _, voting := range votings {
+		res = append(res, n.newVotingResponse(voting))
+	}
+
+	writeJson(w, http.StatusOK, res)
+}
+
+func (n *Node) handleVoting(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+
+	voting, ok := n.findVoting(id)
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Errorf("voting %s not found", id))
+		return
+	}
+
+	writeJson(w, http.StatusOK, n.newVotingResponse(voting))
+}
+
+func (n *Node) handleResults(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+
+	voting, ok := n.findVoting(id)
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Errorf("voting %s not found", id))
+		return
+	}
+
+	n.chainLock.Lock()
+	tally, err := n.Chain.GetTally(id)
+	n.chainLock.Unlock()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, fmt.Errorf("failed to tally voting: %v", err))
+		return
+	}
+
+	writeJson(w, http.StatusOK, ResultsResponse{
+		Voting: n.newVotingResponse(voting),
+		Tally:  tally,
+	})
+}
+
+func (n *Node) handleChain(w http.ResponseWriter, r *http.Request) {
+	n.chainLock.Lock()
+	blocks := n.Chain.GetBlocks(0, n.Chain.Length())
+	n.chainLock.Unlock()
+
+	writeJson(w, http.StatusOK, blocks)
+}
+
+func (n *Node) handleCloseVoting(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+
+	id, err := n.SendCloseVoting(blockchain.NewClose(id))
+	if err != nil {
+		writeError(w, errorStatus(err), err)
+		return
+	}
+
+	writeJson(w, http.StatusOK, SendResponse{ID: id})
+}
+
+func (n *Node) handleBlockByNonce(w http.ResponseWriter, r *http.Request) {
+	nonce, err := strconv.Atoi(r.PathValue("nonce"))
+	if err != nil || nonce < 0 {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid nonce %s", r.PathValue("nonce")))
+		return
+	}
+
+	n.chainLock.Lock()
+	block, ok := n.Chain.GetBlock(nonce)
+	n.chainLock.Unlock()
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Errorf("block #%d not found", nonce))
+		return
+	}
+
+	writeJson(w, http.StatusOK, block)
+}
+
+func (n *Node) handleBlockByHash(w http.ResponseWriter, r *http.Request) {
+	hash := r.PathValue("hash")
+
+	n.chainLock.Lock()
+	block, ok := n.Chain.GetBlockByHash(hash)
+	n.chainLock.Unlock()
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Errorf("block %s not found", hash))
+		return
+	}
+
+	writeJson(w, http.StatusOK, block)
+}
+
+func (n *Node) handleBlocksByAddress(w http.ResponseWriter, r *http.Request) {
+	address := blockchain.Address(r.PathValue("address"))
+
+	n.chainLock.Lock()
+	blocks := n.Chain.GetBlocksByAddress(address)
+	n.chainLock.Unlock()
+
+	writeJson(w, http.StatusOK, blocks)
+}
+
+func (n *Node) handlePeers(w http.ResponseWriter, r *http.Request) {
+	writeJson(w, http.StatusOK, n.PeerAddrs())
+}
+
+func (n *Node) handleStatus(w http.ResponseWriter, r *http.Request) {
+	addr, err := n.Signer.Address()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, fmt.Errorf("failed to get address: %v", err))
+		return
+	}
+
+	n.chainLock.Lock()
+	lastBlock := n.Chain.GetLastBlock()
+	stateRoot := n.Chain.StateRoot()
+	votings := len(n.Chain.GetVotings())
+	consensus := n.Chain.Consensus().Name()
+	finalized := n.Chain.FinalizedHeight()
+	chainID := n.Chain.Genesis().ChainID
+	genesisHash := n.Chain.GenesisBlock().BlockHash
+	n.chainLock.Unlock()
+
+	writeJson(w, http.StatusOK, StatusResponse{
+		Name:          n.Name,
+		ChainID:       chainID,
+		GenesisHash:   genesisHash,
+		Address:       addr,
+		ListenAddr:    n.Transport.Addr(),
+		Height:        lastBlock.Nonce,
+		Finalized:     finalized,
+		LastBlockHash: lastBlock.BlockHash,
+		StateRoot:     stateRoot,
+		Votings:       votings,
+		Pending:       n.Mempool.Len(),
+		Peers:         len(n.PeerAddrs()),
+		HashRate:      n.HashRate(),
+		Consensus:     consensus,
+	})
+}
+
+func (n *Node) findVoting(id string) (blockchain.VotingWithBlock, bool) {
+	n.chainLock.Lock()
+	defer n.chainLock.Unlock()
+
+	return n.Chain.GetVoting(id)
+}
+
+func (n *Node) newVotingResponse(voting blockchain.VotingWithBlock) VotingResponse {
+	n.chainLock.Lock()
+	status, _ := n.Chain.GetVotingStatus(voting.ID)
+	n.chainLock.Unlock()
+
+	return VotingResponse{
+		ID:         voting.ID,
+		Nonce:      voting.Nonce,
+		Creator:    voting.Creator,
+		Title:      voting.Title,
+		Kind:       voting.GetKind(),
+		Options:    voting.AllOptions(),
+		MaxChoices: voting.GetMaxChoices(),
+
+		StartHeight: voting.StartHeight,
+		EndHeight:   voting.EndHeight,
+		StartTime:   voting.StartTime,
+		EndTime:     voting.EndTime,
+		Status:      status,
+
+		Electorate: voting.Electorate,
+		Duplicates: voting.GetDuplicatePolicy(),
+	}
+}
+
+func writeJson(w http.ResponseWriter, status int, payload any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(payload)
+}
+
+func errorStatus(err error) int {
+	switch {
+	case errors.Is(err, blockchain.ErrUnknownVoting):
+		return http.StatusNotFound
+	case errors.Is(err, blockchain.ErrIneligibleVoter),
+		errors.Is(err, blockchain.ErrNotVotingCreator),
+		errors.Is(err, blockchain.ErrNotAdmin):
+		return http.StatusForbidden
+	case errors.Is(err, blockchain.ErrDuplicateVote),
+		errors.Is(err, blockchain.ErrVotingClosed),
+		errors.Is(err, blockchain.ErrVotingNotStarted):
+		return http.StatusConflict
+	case errors.Is(err, ErrMempoolFull),
+		errors.Is(err, ErrTooManyFromSender):
+		return http.StatusServiceUnavailable
+	case errors.Is(err, blockchain.ErrMalformedCall),
+		errors.Is(err, blockchain.ErrMalformedCallData),
+		errors.Is(err, blockchain.ErrUnknownMethod),
+		errors.Is(err, blockchain.ErrEmptyTitle),
+		errors.Is(err, blockchain.ErrInvalidVoting),
+		errors.Is(err, blockchain.ErrUnknownRegistry),
+		errors.Is(err, blockchain.ErrUnknownElectorate),
+		errors.Is(err, blockchain.ErrInvalidBallot):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJson(w, status, ErrorResponse{Error: err.Error()})
+}
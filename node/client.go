@@ -0,0 +1,172 @@
+package node
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/kotsmile/go-vote/blockchain"
+)
+
+type ApiClient struct {
+	baseUrl string
+	client  *http.Client
+}
+
+func NewApiClient(addr string) *ApiClient {
+	baseUrl := addr
+	if !strings.HasPrefix(baseUrl, "http://") && !strings.HasPrefix(baseUrl, "https://") {
+		baseUrl = "http://" + baseUrl
+	}
+
+	return &ApiClient{
+		baseUrl: strings.TrimSuffix(baseUrl, "/"),
+		client:  &http.Client{},
+	}
+}
+
+func (c *ApiClient) CreateVoting(req CreateVotingRequest) (string, error) {
+	var res SendResponse
+	if err := c.do(http.MethodPost, "/votings", req, &res); err != nil {
+		return "", err
+	}
+
+	return res.ID, nil
+}
+
+func (c *ApiClient) CastVote(req CastVoteRequest) (string, error) {
+	var res SendResponse
+	if err := c.do(http.MethodPost, "/votes", req, &res); err != nil {
+		return "", err
+	}
+
+	return res.ID, nil
+}
+
+func (c *ApiClient) CloseVoting(voting string) (string, error) {
+	var res SendResponse
+	if err := c.do(http.MethodPost, "/votings/"+voting+"/close", nil, &res); err != nil {
+		return "", err
+	}
+
+	return res.ID, nil
+}
+
+func (c *ApiClient) Votings() ([]VotingResponse, error) {
+	var res []VotingResponse
+	if err := c.do(http.MethodGet, "/votings", nil, &res); err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+func (c *ApiClient) Voting(voting string) (VotingResponse, error) {
+	var res VotingResponse
+	if err := c.do(http.MethodGet, "/votings/"+voting, nil, &res); err != nil {
+		return VotingResponse{}, err
+	}
+
+	return res, nil
+}
+
+func (c *ApiClient) Results(voting string) (ResultsResponse, error) {
+	var res ResultsResponse
+	if err := c.do(http.MethodGet, "/votings/"+voting+"/results", nil, &res); err != nil {
+		return ResultsResponse{}, err
+	}
+
+	return res, nil
+}
+
+func (c *ApiClient) Chain() ([]blockchain.Block, error) {
+	var res []blockchain.Block
+	if err := c.do(http.MethodGet, "/chain", nil, &res); err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+func (c *ApiClient) Block(nonce int) (blockchain.Block, error) {
+	var res blockchain.Block
+	if err := c.do(http.MethodGet, "/blocks/"+strconv.Itoa(nonce), nil, &res); err != nil {
+		return blockchain.Block{}, err
+	}
+
+	return res, nil
+}
+
+func (c *ApiClient) BlockByHash(hash string) (blockchain.Block, error) {
+	var res blockchain.Block
+	if err := c.do(http.MethodGet, "/blocks/hash/"+hash, nil, &res); err != nil {
+		return blockchain.Block{}, err
+	}
+
+	return res, nil
+}
+
+func (c *ApiClient) BlocksByAddress(address blockchain.Address) ([]blockchain.Block, error) {
+	var res []blockchain.Block
+	if err := c.do(http.MethodGet, "/addresses/"+string(address)+"/blocks", nil, &res); err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+func (c *ApiClient) Peers() ([]string, error) {
+	var res []string
+	if err := c.do(http.MethodGet, "/peers", nil, &res); err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+func (c *ApiClient) Status() (StatusResponse, error) {
+	var res StatusResponse
+	if err := c.do(http.MethodGet, "/status", nil, &res); err != nil {
+		return StatusResponse{}, err
+	}
+
+	return res, nil
+}
+
+func (c *ApiClient) do(method string, path string, body any, res any) error {
+	var reqBody bytes.Buffer
+	if body != nil {
+		if err := json.NewEncoder(&reqBody).Encode(body); err != nil {
+			return fmt.Errorf("failed to serialize %+v: %v", body, err)
+		}
+	}
+
+	req, err := http.NewRequest(method, c.baseUrl+path, &reqBody)
+	if err != nil {
+		return fmt.Errorf("failed to create request %s %s: %v", method, path, err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to call %s %s: %v", method, path, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		var errRes ErrorResponse
+		if err := json.NewDecoder(resp.Body).Decode(&errRes); err != nil {
+			return fmt.Errorf("%s %s: %s", method, path, resp.Status)
+		}
+		return fmt.Errorf("%s %s: %s", method, path, errRes.Error)
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(res); err != nil {
+		return fmt.Errorf("failed to deserialize response of %s %s: %v", method, path, err)
+	}
+
+	return nil
+}
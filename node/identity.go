@@ -0,0 +1,98 @@
+package node
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/kotsmile/go-vote/blockchain"
+	"github.com/kotsmile/go-vote/p2p"
+)
+
+// WalletIdentity proves ownership of node address during handshake, id
+// of node is address of its wallet.
+type WalletIdentity struct {
+	wallet  blockchain.Wallet
+	address blockchain.Address
+}
+
+var _ p2p.Identity = WalletIdentity{}
+
+func NewWalletIdentity(wallet blockchain.Wallet) (WalletIdentity, error) {
+	address, err := wallet.Address()
+	if err != nil {
+		return WalletIdentity{}, fmt.Errorf("failed to get address: %v", err)
+	}
+
+	return WalletIdentity{
+		wallet:  wallet,
+		address: address,
+	}, nil
+}
+
+func (i WalletIdentity) ID() string {
+	return string(i.address)
+}
+
+func (i WalletIdentity) Sign(data []byte) (string, error) {
+	signature, err := i.wallet.Sign(data)
+	if err != nil {
+		return "", err
+	}
+
+	return string(signature), nil
+}
+
+func (i WalletIdentity) Verify(id string, data []byte, signature string) bool {
+	ok, err := blockchain.Signature(signature).Verify(blockchain.Address(id), data)
+	return err == nil && ok
+}
+
+func (i WalletIdentity) KeyID(key crypto.PublicKey) (string, error) {
+	publicKey, ok := key.(*ecdsa.PublicKey)
+	if !ok {
+		return "", fmt.Errorf("unsupported key %T", key)
+	}
+
+	return string(blockchain.NewAddressFromPublicKey(publicKey)), nil
+}
+
+// NewWalletCertificate returns self-signed tls certificate of wallet key,
+// so peers of secure transport are authenticated by node address.
+func NewWalletCertificate(wallet blockchain.Wallet) (tls.Certificate, error) {
+	privateKey, err := wallet.PrivateKey()
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("failed to get private key: %v", err)
+	}
+
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("failed to generate serial: %v", err)
+	}
+
+	address := blockchain.NewAddressFromPublicKey(&privateKey.PublicKey)
+	template := x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: string(address[:16])},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().AddDate(10, 0, 0),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, &template, &template, &privateKey.PublicKey, privateKey)
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("failed to create certificate: %v", err)
+	}
+
+	return tls.Certificate{
+		Certificate: [][]byte{der},
+		PrivateKey:  privateKey,
+	}, nil
+}
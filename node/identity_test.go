@@ -0,0 +1,82 @@
+package node
+
+import (
+	"crypto"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/kotsmile/go-vote/blockchain"
+	"github.com/kotsmile/go-vote/p2p"
+)
+
+// scriptedConn is handshake connection on which remote side sends
+// prepared messages and ignores what it receives.
+type scriptedConn struct {
+	messages []any
+}
+
+func (c *scriptedConn) PeerKey() crypto.PublicKey {
+	return nil
+}
+
+func (c *scriptedConn) Send(any) error {
+	return nil
+}
+
+func (c *scriptedConn) Receive(data any) error {
+	if len(c.messages) == 0 {
+		return errors.New("no more messages")
+	}
+
+	message, err := json.Marshal(c.messages[0])
+	if err != nil {
+		return err
+	}
+	c.messages = c.messages[1:]
+
+	return json.Unmarshal(message, data)
+}
+
+func TestHandshakeRejectsMalformedProof(t *testing.T) {
+	identity, err := NewWalletIdentity(blockchain.NewRandomWallet())
+	if err != nil {
+		t.Fatalf("failed to create identity: %v", err)
+	}
+	remote, err := blockchain.NewRandomWallet().Address()
+	if err != nil {
+		t.Fatalf("failed to get address: %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		id        string
+		signature string
+	}{
+		{name: "short signature", id: string(remote), signature: "00"},
+		{name: "empty signature", id: string(remote), signature: ""},
+		{name: "not hex signature", id: string(remote), signature: "zz"},
+		{name: "short id", id: "00", signature: "00"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			conn := &scriptedConn{messages: []any{
+				map[string]any{
+					"version":   p2p.ProtocolVersion,
+					"network":   "network",
+					"id":        test.id,
+					"challenge": "00",
+				},
+				map[string]any{"signature": test.signature},
+			}}
+
+			handshake := p2p.NewHandshakeFunc(identity, func() p2p.PeerInfo {
+				return p2p.PeerInfo{Network: "network"}
+			})
+			if _, err := handshake(conn); !errors.Is(err, p2p.ErrInvalidIdentity) {
+				t.Fatalf("expected %v, got %v", p2p.ErrInvalidIdentity, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,150 @@
+package node
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/kotsmile/go-vote/blockchain"
+)
+
+const (
+	// MaxMempoolSize is number of pending transactions node keeps
+	MaxMempoolSize = 10000
+	// MaxSenderTransactions is number of pending transactions of single
+	// address, so one sender can not fill mempool
+	MaxSenderTransactions = 64
+)
+
+var (
+	ErrMempoolFull       = errors.New("mempool is full")
+	ErrTooManyFromSender = errors.New("too many pending transactions of sender")
+)
+
+// Mempool keeps transactions which are not included in chain yet in
+// order they were received.
+type Mempool struct {
+	lock sync.Mutex
+	txs  []blockchain.Transaction
+}
+
+func NewMempool() *Mempool {
+	return &Mempool{
+		txs: make([]blockchain.Transaction, 0),
+	}
+}
+
+func (m *Mempool) Has(hash string) bool {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	return m.has(hash)
+}
+
+func (m *Mempool) has(hash string) bool {
+	for _, tx := range m.txs {
+		if tx.Hash == hash {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Add puts tx to mempool unless mempool or pending transactions of sender
+// are at their limits.
+func (m *Mempool) Add(tx blockchain.Transaction) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if len(m.txs) >= MaxMempoolSize {
+		return ErrMempoolFull
+	}
+	if m.pending(tx.From) >= MaxSenderTransactions {
+		return fmt.Errorf("%w %s", ErrTooManyFromSender, tx.From)
+	}
+
+	m.txs = append(m.txs, tx)
+	return nil
+}
+
+func (m *Mempool) pending(address blockchain.Address) int {
+	count := 0
+	for _, tx := range m.txs {
+		if tx.From == address {
+			count++
+		}
+	}
+
+	return count
+}
+
+// Restore puts back transactions of blocks removed from chain, they go
+// before pending ones since they were accepted earlier. Transactions over
+// limits are dropped starting from the latest.
+func (m *Mempool) Restore(txs []blockchain.Transaction) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	all := make([]blockchain.Transaction, 0, len(txs)+len(m.txs))
+	for _, tx := range txs {
+		if !m.has(tx.Hash) {
+			all = append(all, tx)
+		}
+	}
+	all = append(all, m.txs...)
+
+	restored := make([]blockchain.Transaction, 0, min(len(all), MaxMempoolSize))
+	senders := make(map[blockchain.Address]int)
+	for _, tx := range all {
+		if len(restored) >= MaxMempoolSize {
+			break
+		}
+		if senders[tx.From] >= MaxSenderTransactions {
+			continue
+		}
+
+		senders[tx.From]++
+		restored = append(restored, tx)
+	}
+
+	m.txs = restored
+}
+
+func (m *Mempool) Transactions() []blockchain.Transaction {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	return append([]blockchain.Transaction{}, m.txs...)
+}
+
+func (m *Mempool) Len() int {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	return len(m.txs)
+}
+
+// Sequence returns sequence of last pending transaction of address.
+func (m *Mempool) Sequence(address blockchain.Address) uint64 {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	var sequence uint64 = 0
+	for _, tx := range m.txs {
+		if tx.From == address && tx.Sequence > sequence {
+			sequence = tx.Sequence
+		}
+	}
+
+	return sequence
+}
+
+// Prune drops transactions which were included in chain or are not valid
+// on top of it anymore.
+func (m *Mempool) Prune(chain blockchain.Chain) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	m.txs = chain.FilterTransactions(m.txs)
+}
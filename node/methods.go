@@ -1,7 +1,9 @@
 package node
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 
 	"github.com/kotsmile/go-vote/blockchain"
@@ -26,12 +28,55 @@ func (n *Node) Send(peer p2p.Peer, method p2p.RpcMethod, payload any) error {
 	return nil
 }
 
+// Reply sends response to request rpc.
+func (n *Node) Reply(peer p2p.Peer, request p2p.Rpc, method p2p.RpcMethod, payload any) error {
+	payloadData, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to serialize %+v: %v", payload, err)
+	}
+
+	rpc := request.Reply(method, payloadData)
+
+	if err := peer.Send(rpc); err != nil {
+		return fmt.Errorf("failed to send rpc %+v: %v", rpc, err)
+	}
+
+	return nil
+}
+
+var ErrUnexpectedResponse = errors.New("unexpected response")
+
+// Request sends request to peer and decodes its response of responseMethod
+// into response.
+func (n *Node) Request(ctx context.Context, peer p2p.Peer, method p2p.RpcMethod, payload any, responseMethod p2p.RpcMethod, response any) error {
+	payloadData, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to serialize %+v: %v", payload, err)
+	}
+
+	rpc, err := peer.Request(ctx, method, payloadData)
+	if err != nil {
+		return fmt.Errorf("failed to request %s from %s: %w", method, peer.Addr(), err)
+	}
+	if rpc.Method != responseMethod {
+		return fmt.Errorf("%w %s on %s", ErrUnexpectedResponse, rpc.Method, method)
+	}
+
+	if err := json.Unmarshal(rpc.Payload, response); err != nil {
+		return fmt.Errorf("failed deserialize payload %v: %v", rpc.Payload, err)
+	}
+
+	return nil
+}
+
 const (
 	GetBlock         p2p.RpcMethod = "getBlock"
 	GetBlockResponse p2p.RpcMethod = GetBlock + "Response"
 	BroadcastBlock   p2p.RpcMethod = "broadcastBlock"
 	GetPeers         p2p.RpcMethod = "getPeers"
 	GetPeersResponse p2p.RpcMethod = GetPeers + "Response"
+
+	BroadcastTransaction p2p.RpcMethod = "broadcastTransaction"
 )
 
 type GetBlockPayload struct {
@@ -40,7 +85,6 @@ type GetBlockPayload struct {
 
 type GetBlockResponsePayload struct {
 	Block blockchain.Block `json:"block"`
-	Nonce int              `json:"nonce"`
 }
 
 type BroadcastBlockPayload struct {
@@ -52,3 +96,7 @@ type GetPeersPayload struct{}
 type GetPeersResponsePayload struct {
 	Peers []string `json:"peers"`
 }
+
+type BroadcastTransactionPayload struct {
+	Transaction blockchain.Transaction `json:"transaction"`
+}
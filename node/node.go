@@ -1,44 +1,100 @@
 package node
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
-	"math"
+	"net/http"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/kotsmile/go-vote/blockchain"
 	"github.com/kotsmile/go-vote/p2p"
 )
 
+const (
+	MineInterval = time.Second * 5
+
+	DefaultQuarantinePath = "chain"
+)
+
+var ErrNothingToMine = errors.New("no transactions to mine")
+
 type Node struct {
 	Name      string
 	Signer    blockchain.Wallet
 	Transport p2p.Transport
+
+	peersLock sync.RWMutex
 	Peers     map[string]p2p.Peer
 
 	chainLock sync.Mutex
 	Chain     blockchain.Chain
 
-	// TODO: make atomic
-	conflictsLock sync.Mutex
-	conflicts     map[string]bool
+	Mempool    *Mempool
+	Mining     bool
+	mineCh     chan struct{}
+	mineLock   sync.Mutex
+	mineCancel context.CancelFunc
+	hashRate   atomic.Uint64
+
+	// quarantine is base path of files with blocks dropped by recovery
+	quarantine string
+	recovery   blockchain.Recovery
+
+	// syncing holds addresses of peers blocks are downloaded from
+	syncing sync.Map
+	blockCh chan queuedBlock
+
+	apiLock   sync.Mutex
+	apiServer *http.Server
+	quitCh    chan struct{}
+	stopOnce  sync.Once
+}
+
+// NewNode loads chain file, corrupted file is recovered by keeping its
+// readable prefix.
+func NewNode(filepath string, transport p2p.Transport, signer blockchain.Wallet) (*Node, error) {
+	chain, recovery, err := blockchain.RecoverChainFromFile(filepath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load chain: %v", err)
+	}
+
+	n, err := NewNodeWithChain(chain, transport, signer)
+	if err != nil {
+		return nil, err
+	}
+
+	n.WithQuarantinePath(filepath).WithRecovery(recovery)
+	return n, nil
 }
 
-func NewNode(filepath string, transport p2p.Transport, signer blockchain.Wallet) *Node {
-	chain, _ := blockchain.NewChainFromFile(filepath)
+// NewNodeWithChain creates node on top of already loaded chain, e.g. one
+// backed by block store.
+func NewNodeWithChain(chain blockchain.Chain, transport p2p.Transport, signer blockchain.Wallet) (*Node, error) {
+	identity, err := NewWalletIdentity(signer)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create identity: %v", err)
+	}
 
 	server := Node{
-		Transport: transport,
-		Signer:    signer,
-		Chain:     chain,
-		Peers:     make(map[string]p2p.Peer),
-		conflicts: make(map[string]bool),
+		Transport:  transport,
+		Signer:     signer,
+		Chain:      chain,
+		Peers:      make(map[string]p2p.Peer),
+		Mempool:    NewMempool(),
+		mineCh:     make(chan struct{}, 1),
+		blockCh:    make(chan queuedBlock, MaxQueuedBlocks),
+		quarantine: DefaultQuarantinePath,
+		quitCh:     make(chan struct{}),
 	}
 
 	transport.SetOnPeer(server.onPeer)
-	return &server
+	transport.SetOnPeerClose(server.onPeerClose)
+	transport.SetHandshake(p2p.NewHandshakeFunc(identity, server.peerInfo))
+	return &server, nil
 }
 
 func (n *Node) WithName(name string) *Node {
@@ -46,21 +102,73 @@ func (n *Node) WithName(name string) *Node {
 	return n
 }
 
+// WithQuarantinePath sets base path of files where blocks dropped while
+// recovering invalid chain are kept.
+func (n *Node) WithQuarantinePath(path string) *Node {
+	n.quarantine = path
+	return n
+}
+
+// WithRecovery reports recovery done while loading chain on start.
+func (n *Node) WithRecovery(recovery blockchain.Recovery) *Node {
+	n.recovery = recovery
+	return n
+}
+
+// WithMining makes node seal pending transactions into blocks.
+func (n *Node) WithMining(mining bool) *Node {
+	n.Mining = mining
+	return n
+}
+
+// WithGenesis makes node join network of genesis, which must be valid.
+// Consensus and finality depth of network come from genesis.
+func (n *Node) WithGenesis(genesis blockchain.Genesis) (*Node, error) {
+	chain, err := n.Chain.WithGenesis(genesis)
+	if err != nil {
+		return nil, fmt.Errorf("failed to join network %s: %v", genesis.ChainID, err)
+	}
+
+	n.Chain = chain
+	return n, nil
+}
+
 func (n *Node) Log(msg string) {
 	fmt.Printf("[%s] %s\n", n.Name, msg)
 }
 
+// Start validates chain once and serves peers, later blocks are validated
+// one by one as they are added.
 func (n *Node) Start(verbose bool) error {
+	if n.recovery.Recovered() {
+		n.logRecovery(n.recovery)
+	}
+	if err := n.recoverChain(); err != nil {
+		return err
+	}
+
 	if err := n.Transport.ListenAndAccept(); err != nil {
 		return fmt.Errorf("failed to start transport")
 	}
 
-	// TODO: if my chain is incorrect resync full
 	go n.Sync()
+	for range BlockWorkers {
+		go n.blockWorker(verbose)
+	}
+
+	if n.Mining {
+		go n.mineLoop()
+	}
 
 	for {
-		rpc := <-n.Transport.Consume()
-		peer, ok := n.Peers[rpc.From]
+		var rpc p2p.Rpc
+		select {
+		case rpc = <-n.Transport.Consume():
+		case <-n.quitCh:
+			return nil
+		}
+
+		peer, ok := n.GetPeer(rpc.From)
 		if !ok {
 			n.Log(fmt.Sprintf("unknown sender %s", rpc.From))
 			continue
@@ -74,244 +182,162 @@ func (n *Node) Start(verbose bool) error {
 				continue
 			}
 
-			block := n.Chain.GetLastBlock()
-			if payload.Nonce != -1 && payload.Nonce < n.Chain.Length() {
-				block, ok = n.Chain.GetBlock(payload.Nonce)
-				if !ok {
-					continue
-				}
+			block, ok := n.requestedBlock(payload.Nonce)
+			if !ok {
+				n.Log(fmt.Sprintf("invalid nonce %d requested by %s", payload.Nonce, rpc.From))
+				continue
 			}
 
-			if err := n.Send(peer, GetBlockResponse, GetBlockResponsePayload{
+			if err := n.Reply(peer, rpc, GetBlockResponse, GetBlockResponsePayload{
 				Block: block,
-				Nonce: payload.Nonce,
 			}); err != nil {
 				n.Log(fmt.Sprintf("failed to send response on %s: %v", rpc.Method, err))
 				continue
 			}
-		case GetBlockResponse:
-			var payload GetBlockResponsePayload
-
-			if err := json.Unmarshal(rpc.Payload, &payload); err != nil {
-				fmt.Printf("failed deserialize payload %v: %v", rpc.Payload, err)
-				continue
-			}
-
-			lastBlock := n.Chain.GetLastBlock()
-			if payload.Nonce == -1 {
-				if lastBlock.Nonce < payload.Block.Nonce {
-					fmt.Printf("start syncing past blocks\n")
-					if err := n.Send(peer, GetBlock, GetBlockPayload{
-						Nonce: int(lastBlock.Nonce + 1),
-					}); err != nil {
-						return fmt.Errorf("failed to send %s: %v", GetBlock, err)
-					}
-				} else if lastBlock.Nonce > payload.Block.Nonce {
-					fmt.Printf("start checking past blocks\n")
-					if err := n.Send(peer, GetBlock, GetBlockPayload{
-						Nonce: int(payload.Block.Nonce),
-					}); err != nil {
-						return fmt.Errorf("failed to send %s: %v", GetBlock, err)
-					}
-				}
-			} else if lastBlock.Nonce == payload.Block.Nonce-1 {
-				// n.Log(fmt.Sprintf("lastBlock.Nonce: %d; payload.Block.Nonce", lastBlock.Nonce, payload.Block.Nonce))
-
-				n.chainLock.Lock()
-				ok, err := n.Chain.PushBlock(payload.Block)
-				n.chainLock.Unlock()
-
-				if err != nil {
-					if errors.Is(err, blockchain.ErrIncorrectPrevBlockHash) {
-						n.conflictsLock.Lock()
-						n.conflicts[rpc.From] = true
-
-						total := len(n.Peers)
-						conflicts := 0
-						for addr := range n.Peers {
-							if n.conflicts[addr] {
-								conflicts++
-							}
-						}
-						n.Log(fmt.Sprintf("total: %d; conflicts: %d", total, conflicts))
-
-						n.conflictsLock.Unlock()
-
-						if conflicts > total/2 {
-							n.Log("reseting chain")
-							n.conflictsLock.Lock()
-							n.conflicts = make(map[string]bool)
-							n.conflictsLock.Unlock()
-
-							n.chainLock.Lock()
-							n.Chain.Reset()
-							n.chainLock.Unlock()
-
-							if err := n.Send(peer, GetBlock, GetBlockPayload{
-								Nonce: 1,
-							}); err != nil {
-								return fmt.Errorf("failed to send %s: %v", GetBlock, err)
-							}
-
-						}
-
-						continue
-					}
-					if !errors.Is(err, blockchain.ErrBlockIncluded) {
-						fmt.Printf("failed to push block: %v\n", err)
-					}
-
-					continue
-				}
-				if !ok {
-					fmt.Printf("not ok\n")
-				}
-
-				if err := n.Send(peer, GetBlock, GetBlockPayload{
-					Nonce: int(payload.Block.Nonce + 1),
-				}); err != nil {
-					return fmt.Errorf("failed to send %s: %v", GetBlock, err)
-				}
-
-			} else {
-				localBlock, ok := n.Chain.GetBlock(payload.Nonce)
-				if !ok {
-					continue
-				}
-				if localBlock.Equal(payload.Block) {
-					continue
-				}
-
-				n.conflictsLock.Lock()
-				n.conflicts[rpc.From] = true
-
-				total := len(n.Peers)
-				conflicts := 0
-				for addr := range n.Peers {
-					if n.conflicts[addr] {
-						conflicts++
-					}
-				}
-				n.Log(fmt.Sprintf("total: %d; conflicts: %d", total, conflicts))
-
-				n.conflictsLock.Unlock()
-
-				if conflicts > total/2 {
-					n.Log("reseting chain")
-					n.conflictsLock.Lock()
-					n.conflicts = make(map[string]bool)
-					n.conflictsLock.Unlock()
-
-					n.chainLock.Lock()
-					n.Chain.Reset()
-					n.chainLock.Unlock()
-
-					if err := n.Send(peer, GetBlock, GetBlockPayload{
-						Nonce: 1,
-					}); err != nil {
-						return fmt.Errorf("failed to send %s: %v", GetBlock, err)
-					}
-
-				}
-
-			}
 		case GetPeers:
+			// peers are shared by addresses they listen on, remote
+			// addresses of inbound connections can not be dialed
 			var peers []string
-			for addr := range n.Peers {
-				if addr != rpc.From {
-					peers = append(peers, addr)
+			for _, other := range n.peerList() {
+				if other.Addr() != rpc.From {
+					peers = append(peers, p2p.DialAddr(other))
 				}
 			}
 
-			if err := n.Send(peer, GetPeersResponse, GetPeersResponsePayload{
+			if err := n.Reply(peer, rpc, GetPeersResponse, GetPeersResponsePayload{
 				Peers: peers,
 			}); err != nil {
 				n.Log(fmt.Sprintf("failed to send response on %s: %v", rpc.Method, err))
 				continue
 			}
-		case GetPeersResponse:
-			var payload GetPeersResponsePayload
+		case BroadcastBlock:
+			var payload BroadcastBlockPayload
+
 			if err := json.Unmarshal(rpc.Payload, &payload); err != nil {
 				n.Log(fmt.Sprintf("failed deserialize payload %v: %v", rpc.Payload, err))
 				continue
 			}
 
-			for _, peerAddr := range payload.Peers {
-				_, ok := n.Peers[peerAddr]
-				if ok {
-					continue
-				}
-
-				if err := n.Connect(peerAddr); err != nil {
-					n.Log(fmt.Sprintf("failed to connect %s: %v", peerAddr, err))
-					continue
-				}
+			// missing ancestors are downloaded from peer, so block is
+			// synced by workers not to hold other rpcs
+			if !n.queueBlock(peer, payload) {
+				n.Log(fmt.Sprintf("dropping block %s from %s: queue is full", payload.Block.BlockHash, rpc.From))
 			}
-		case BroadcastBlock:
-			var payload BroadcastBlockPayload
+		case BroadcastTransaction:
+			var payload BroadcastTransactionPayload
 
 			if err := json.Unmarshal(rpc.Payload, &payload); err != nil {
 				n.Log(fmt.Sprintf("failed deserialize payload %v: %v", rpc.Payload, err))
 				continue
 			}
 
-			n.chainLock.Lock()
-			ok, err := n.Chain.PushBlock(payload.Block)
-			n.chainLock.Unlock()
+			added, err := n.AddTransaction(payload.Transaction)
 			if err != nil {
-				if !errors.Is(err, blockchain.ErrBlockIncluded) {
-					n.Log(fmt.Sprintf("failed to push block: %v", err))
-				}
+				n.Log(fmt.Sprintf("failed to add transaction %s: %v", payload.Transaction.Hash, err))
 				continue
 			}
-			if !ok {
-				n.Log(fmt.Sprintf("not ok"))
-			}
-
-			if verbose {
-				n.Log(payload.Block.String())
+			if !added {
+				continue
 			}
 
-			if err := n.BroadcastExcept(BroadcastBlock, payload, rpc.From); err != nil {
-				n.Log(fmt.Sprintf("failed to broadcast block: %v", err))
+			if err := n.BroadcastExcept(BroadcastTransaction, payload, rpc.From); err != nil {
+				n.Log(fmt.Sprintf("failed to broadcast transaction: %v", err))
 				continue
 			}
 		}
 	}
 }
 
+// Stop shuts node down, every step runs even when previous one failed, so
+// chain is flushed whatever happens to api and transport.
+func (n *Node) Stop() error {
+	var err error
+
+	n.stopOnce.Do(func() {
+		errs := make([]error, 0)
+
+		close(n.quitCh)
+		n.cancelMining()
+
+		n.apiLock.Lock()
+		apiServer := n.apiServer
+		n.apiLock.Unlock()
+		if apiServer != nil {
+			if apiErr := apiServer.Close(); apiErr != nil {
+				errs = append(errs, fmt.Errorf("failed to close api: %v", apiErr))
+			}
+		}
+
+		if transportErr := n.Transport.Close(); transportErr != nil {
+			errs = append(errs, fmt.Errorf("failed to close transport: %v", transportErr))
+		}
+
+		n.chainLock.Lock()
+		defer n.chainLock.Unlock()
+		if saveErr := n.Chain.SaveFile(); saveErr != nil {
+			errs = append(errs, fmt.Errorf("failed to save chain: %v", saveErr))
+		}
+		if closeErr := n.Chain.Close(); closeErr != nil {
+			errs = append(errs, fmt.Errorf("failed to close chain: %v", closeErr))
+		}
+
+		err = errors.Join(errs...)
+	})
+
+	return err
+}
+
 func (n *Node) Connect(addr string) error {
 	n.Log(fmt.Sprintf("connecting %s", addr))
 
 	if err := n.Transport.Dial(addr); err != nil {
-		return fmt.Errorf("failed to dial %s: %v", addr, err)
+		return fmt.Errorf("failed to dial %s: %w", addr, err)
 	}
 
 	return nil
 }
 
 func (n *Node) Sync() {
-	for {
-		<-time.Tick(time.Second * 10)
+	ticker := time.NewTicker(time.Second * 10)
+	defer ticker.Stop()
 
-		ok, _ := n.Chain.Validate()
-		if !ok {
-			n.Log("invalid chain file; reseting chain")
-			n.chainLock.Lock()
-			n.Chain.Reset()
-			n.chainLock.Unlock()
+	for {
+		select {
+		case <-ticker.C:
+		case <-n.quitCh:
+			return
 		}
 
 		n.Log("syncing")
-		for _, peer := range n.Peers {
-			if err := n.Send(peer, GetBlock, GetBlockPayload{
-				Nonce: -1,
-			}); err != nil {
-			}
+		for _, peer := range n.peerList() {
+			go n.syncPeer(peer)
 		}
 	}
 }
 
+// recoverChain cuts chain to its longest valid prefix, dropped blocks are
+// kept in quarantine file. It replays the whole chain, so it runs only on
+// start.
+func (n *Node) recoverChain() error {
+	n.chainLock.Lock()
+	defer n.chainLock.Unlock()
+
+	recovery, err := n.Chain.Recover(n.quarantine)
+	if err != nil {
+		return fmt.Errorf("failed to recover chain: %v", err)
+	}
+	if recovery.Recovered() {
+		n.Mempool.Prune(n.Chain)
+		n.logRecovery(recovery)
+	}
+
+	return nil
+}
+
+func (n *Node) logRecovery(recovery blockchain.Recovery) {
+	n.Log(fmt.Sprintf("invalid chain; kept %d blocks, moved the rest to %s", recovery.Kept, recovery.Quarantine))
+}
+
 func (n *Node) SendVoting(voting blockchain.Voting) (string, error) {
 	return n.SendData(blockchain.VotingMethod, voting.Data())
 }
@@ -320,51 +346,228 @@ func (n *Node) SendVote(vote blockchain.Vote) (string, error) {
 	return n.SendData(blockchain.VoteMethod, vote.Data())
 }
 
+func (n *Node) SendCloseVoting(closeCall blockchain.Close) (string, error) {
+	return n.SendData(blockchain.CloseMethod, closeCall.Data())
+}
+
+// SendData signs call as transaction of node wallet and gossips it to
+// peers, returned hash identifies transaction.
 func (n *Node) SendData(method blockchain.Method, data []byte) (string, error) {
 	call := blockchain.Call{
 		Method: method,
 		Data:   data,
 	}
 
-	callData, err := json.Marshal(call)
+	addr, err := n.Signer.Address()
 	if err != nil {
-		return "", fmt.Errorf("failed to serialize call %+v: %v", call, err)
+		return "", fmt.Errorf("failed to get address: %v", err)
 	}
 
-	lastBlock := n.Chain.GetLastBlock()
+	n.chainLock.Lock()
+	sequence := max(n.Chain.GetSequence(addr), n.Mempool.Sequence(addr)) + 1
+	network := n.Chain.GenesisBlock().BlockHash
+	n.chainLock.Unlock()
 
-	newBlock, err := blockchain.NewBlock(lastBlock, n.Signer, callData)
+	tx, err := blockchain.NewTransaction(n.Signer, network, sequence, call)
 	if err != nil {
-		return "", fmt.Errorf("failed to create new block: %v", err)
+		return "", fmt.Errorf("failed to create transaction: %v", err)
 	}
 
-	if err := newBlock.Mine(0, math.MaxUint64); err != nil {
-		return "", fmt.Errorf("failed to mine block %+v: %v", newBlock, err)
+	if _, err := n.AddTransaction(tx); err != nil {
+		return "", fmt.Errorf("failed to add transaction %s: %w", tx.Hash, err)
 	}
 
-	if err := newBlock.Sign(); err != nil {
-		return "", fmt.Errorf("failed to sign block %+v: %v", newBlock, err)
+	if err := n.BroadcastExcept(BroadcastTransaction, BroadcastTransactionPayload{
+		Transaction: tx,
+	}, ""); err != nil {
+		return "", fmt.Errorf("failed to broadcast transaction: %v", err)
+	}
+
+	return tx.Hash, nil
+}
+
+// AddTransaction validates tx on top of chain and pending transactions
+// and puts it to mempool, false is returned for already known ones.
+func (n *Node) AddTransaction(tx blockchain.Transaction) (bool, error) {
+	n.chainLock.Lock()
+	defer n.chainLock.Unlock()
+
+	if n.Mempool.Has(tx.Hash) {
+		return false, nil
+	}
+
+	if err := n.Chain.ValidatePendingTransaction(n.Mempool.Transactions(), tx); err != nil {
+		return false, err
+	}
+
+	if err := n.Mempool.Add(tx); err != nil {
+		return false, err
+	}
+
+	select {
+	case n.mineCh <- struct{}{}:
+	default:
 	}
 
+	return true, nil
+}
+
+// MineBlock seals valid pending transactions into new block, pushes it to
+// chain and broadcasts it to peers.
+func (n *Node) MineBlock() (blockchain.Block, error) {
 	n.chainLock.Lock()
-	ok, res := n.Chain.PushBlock(newBlock)
+	txs, _ := n.Chain.SelectTransactions(n.Mempool.Transactions())
+	if len(txs) == 0 {
+		n.chainLock.Unlock()
+		return blockchain.Block{}, ErrNothingToMine
+	}
+
+	newBlock, err := blockchain.NewBlockWithTransactions(n.Chain.GetLastBlock(), n.Signer, txs)
+	if err != nil {
+		n.chainLock.Unlock()
+		return blockchain.Block{}, fmt.Errorf("failed to create new block: %v", err)
+	}
+
+	consensus := n.Chain.Consensus()
+	if err := consensus.Prepare(n.Chain, &newBlock); err != nil {
+		n.chainLock.Unlock()
+		return blockchain.Block{}, err
+	}
+	newBlock.StateRoot = n.Chain.NextStateRoot(newBlock)
 	n.chainLock.Unlock()
-	if !ok {
-		return "", fmt.Errorf("failed to push block %+v: %s", newBlock, res)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	n.mineLock.Lock()
+	n.mineCancel = cancel
+	n.mineLock.Unlock()
+
+	stats, err := consensus.Seal(ctx, &newBlock)
+
+	n.mineLock.Lock()
+	n.mineCancel = nil
+	n.mineLock.Unlock()
+	cancel()
+
+	n.hashRate.Store(uint64(stats.HashRate()))
+	if err != nil {
+		if errors.Is(err, blockchain.ErrMiningCanceled) {
+			return blockchain.Block{}, err
+		}
+		return blockchain.Block{}, fmt.Errorf("failed to seal block %+v: %v", newBlock, err)
+	}
+
+	if err := newBlock.Sign(); err != nil {
+		return blockchain.Block{}, fmt.Errorf("failed to sign block %+v: %v", newBlock, err)
+	}
+
+	res, err := n.addBlock(newBlock)
+	if err != nil {
+		return blockchain.Block{}, fmt.Errorf("failed to add block %s: %w", newBlock.BlockHash, err)
+	}
+	if res.Status == blockchain.BlockSide {
+		return blockchain.Block{}, fmt.Errorf("block %s is not on best chain", newBlock.BlockHash)
 	}
 
 	if err := n.BroadcastExcept(BroadcastBlock, BroadcastBlockPayload{
 		Block: newBlock,
 	}, ""); err != nil {
-		return "", fmt.Errorf("failed to broadcast new block")
+		return blockchain.Block{}, fmt.Errorf("failed to broadcast new block")
 	}
 
-	return newBlock.BlockHash, nil
+	return newBlock, nil
+}
+
+func (n *Node) mineLoop() {
+	ticker := time.NewTicker(MineInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-n.mineCh:
+		case <-ticker.C:
+		case <-n.quitCh:
+			return
+		}
+
+		if n.Mempool.Len() == 0 {
+			continue
+		}
+
+		block, err := n.MineBlock()
+		if err != nil {
+			if errors.Is(err, blockchain.ErrMiningCanceled) {
+				n.Log("mining canceled; restarting on new tip")
+				continue
+			}
+			if !errors.Is(err, ErrNothingToMine) && !errors.Is(err, blockchain.ErrNotProposer) {
+				n.Log(fmt.Sprintf("failed to mine block: %v", err))
+			}
+			continue
+		}
+		n.Log(fmt.Sprintf("mined block #%d with %d transactions at %d H/s", block.Nonce, len(block.Transactions), n.HashRate()))
+	}
+}
+
+// addBlock puts block to chain and keeps mempool in sync with main chain,
+// transactions of blocks removed by reorg are returned to mempool.
+func (n *Node) addBlock(block blockchain.Block) (blockchain.AddResult, error) {
+	n.chainLock.Lock()
+	defer n.chainLock.Unlock()
+
+	res, err := n.Chain.AddBlock(block)
+	if err != nil || res.Status == blockchain.BlockSide {
+		return res, err
+	}
+
+	if res.Status == blockchain.BlockReorged {
+		n.Log(fmt.Sprintf("reorganized to block #%d %s, %d blocks removed", block.Nonce, block.BlockHash, len(res.Removed)))
+
+		txs := make([]blockchain.Transaction, 0)
+		for _, removed := range res.Removed {
+			txs = append(txs, removed.Transactions...)
+		}
+		n.Mempool.Restore(txs)
+	}
+
+	n.Mempool.Prune(n.Chain)
+	n.restartMining()
+	return res, nil
+}
+
+// restartMining aborts block being mined, since its parent is not the tip
+// anymore, and wakes mining loop to start over on new tip.
+func (n *Node) restartMining() {
+	if !n.cancelMining() {
+		return
+	}
+
+	select {
+	case n.mineCh <- struct{}{}:
+	default:
+	}
+}
+
+func (n *Node) cancelMining() bool {
+	n.mineLock.Lock()
+	defer n.mineLock.Unlock()
+
+	if n.mineCancel == nil {
+		return false
+	}
+	n.mineCancel()
+	n.mineCancel = nil
+	return true
+}
+
+// HashRate returns hashes per second of last mined block.
+func (n *Node) HashRate() uint64 {
+	return n.hashRate.Load()
 }
 
 func (n *Node) BroadcastExcept(method p2p.RpcMethod, payload any, exceptAddress string) error {
-	for addr, peer := range n.Peers {
-		if peer.Addr() == exceptAddress {
+	for _, peer := range n.peerList() {
+		addr := peer.Addr()
+		if addr == exceptAddress {
 			continue
 		}
 		err := n.Send(peer, method, payload)
@@ -376,18 +579,106 @@ func (n *Node) BroadcastExcept(method p2p.RpcMethod, payload any, exceptAddress
 	return nil
 }
 
-func (n *Node) onPeer(peer p2p.Peer) error {
-	n.Peers[peer.Addr()] = peer
+// requestedBlock returns block with nonce requested by peer, the last
+// block is returned for -1 and nonces above the tip.
+func (n *Node) requestedBlock(nonce int) (blockchain.Block, bool) {
+	if nonce < -1 {
+		return blockchain.Block{}, false
+	}
+
+	n.chainLock.Lock()
+	defer n.chainLock.Unlock()
+
+	if nonce == -1 || nonce >= n.Chain.Length() {
+		return n.Chain.GetLastBlock(), true
+	}
+	return n.Chain.GetBlock(nonce)
+}
+
+func (n *Node) GetPeer(addr string) (p2p.Peer, bool) {
+	n.peersLock.RLock()
+	defer n.peersLock.RUnlock()
 
-	if err := n.Send(peer, GetBlock, GetBlockPayload{
-		Nonce: -1,
-	}); err != nil {
-		n.Log(fmt.Sprintf("failed to send %s to %s: %v", GetBlock, peer.Addr(), err))
+	peer, ok := n.Peers[addr]
+	return peer, ok
+}
+
+func (n *Node) PeerAddrs() []string {
+	n.peersLock.RLock()
+	defer n.peersLock.RUnlock()
+
+	addrs := make([]string, 0, len(n.Peers))
+	for addr := range n.Peers {
+		addrs = append(addrs, addr)
+	}
+	return addrs
+}
+
+// knowsPeer reports whether node is connected to peer listening on addr.
+func (n *Node) knowsPeer(addr string) bool {
+	for _, peer := range n.peerList() {
+		if peer.Addr() == addr || p2p.DialAddr(peer) == addr {
+			return true
+		}
 	}
 
-	if err := n.Send(peer, GetPeers, GetPeersPayload{}); err != nil {
-		n.Log(fmt.Sprintf("failed to send %s to %s: %v", GetPeers, peer.Addr(), err))
+	return false
+}
+
+func (n *Node) peerList() []p2p.Peer {
+	n.peersLock.RLock()
+	defer n.peersLock.RUnlock()
+
+	peers := make([]p2p.Peer, 0, len(n.Peers))
+	for _, peer := range n.Peers {
+		peers = append(peers, peer)
 	}
+	return peers
+}
+
+// peerInfo describes node to peers during handshake.
+func (n *Node) peerInfo() p2p.PeerInfo {
+	n.chainLock.Lock()
+	defer n.chainLock.Unlock()
+
+	return p2p.PeerInfo{
+		Network:    n.Chain.GenesisBlock().BlockHash,
+		ListenAddr: n.Transport.Addr(),
+		Height:     n.Chain.GetLastBlock().Nonce,
+	}
+}
+
+func (n *Node) onPeer(peer p2p.Peer) error {
+	n.peersLock.Lock()
+	for _, known := range n.Peers {
+		if id := peer.Info().ID; id != "" && known.Info().ID == id {
+			n.peersLock.Unlock()
+			return fmt.Errorf("already connected to %s", id)
+		}
+	}
+	n.Peers[peer.Addr()] = peer
+	n.peersLock.Unlock()
+
+	info := peer.Info()
+	n.Log(fmt.Sprintf("connected %s (listen %s, height %d)", peer.Addr(), info.ListenAddr, info.Height))
+
+	// responses are read only after onPeer returns
+	go n.syncPeer(peer)
+	go n.discoverPeers(peer)
 
 	return nil
 }
+
+// onPeerClose forgets peer whose connection is closed, so it can connect
+// again and is not picked for sync or broadcast.
+func (n *Node) onPeerClose(peer p2p.Peer) {
+	n.peersLock.Lock()
+	if n.Peers[peer.Addr()] != peer {
+		n.peersLock.Unlock()
+		return
+	}
+	delete(n.Peers, peer.Addr())
+	n.peersLock.Unlock()
+
+	n.Log(fmt.Sprintf("disconnected %s", peer.Addr()))
+}
@@ -0,0 +1,239 @@
+package node
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/kotsmile/go-vote/blockchain"
+	"github.com/kotsmile/go-vote/p2p"
+)
+
+const waitTimeout = time.Second * 10
+
+// newTestNode starts node with fresh chain on local network.
+func newTestNode(t *testing.T, network *p2p.LocalNetwork, addr string) *Node {
+	t.Helper()
+
+	chain := blockchain.NewChain([]blockchain.Block{blockchain.GenesisBlock})
+	n, err := NewNodeWithChain(chain, network.NewTransport(addr), blockchain.NewRandomWallet())
+	if err != nil {
+		t.Fatalf("failed to create node: %v", err)
+	}
+	n.WithName(addr)
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- n.Start(false)
+	}()
+	t.Cleanup(func() {
+		if err := n.Stop(); err != nil {
+			t.Errorf("failed to stop %s: %v", addr, err)
+		}
+		if err := <-errCh; err != nil {
+			t.Errorf("failed to run %s: %v", addr, err)
+		}
+	})
+
+	return n
+}
+
+// connect dials node listening on addr, waiting until it listens.
+func connect(t *testing.T, n *Node, addr string) {
+	t.Helper()
+
+	var err error
+	waitFor(t, addr+" to listen", func() bool {
+		err = n.Connect(addr)
+		return !errors.Is(err, p2p.ErrTransportNotFound)
+	})
+	if err != nil {
+		t.Fatalf("failed to connect %s: %v", addr, err)
+	}
+}
+
+// mineVoting sends voting from node and mines block with it.
+func mineVoting(t *testing.T, n *Node) blockchain.Block {
+	t.Helper()
+
+	if _, err := n.SendVoting(blockchain.Voting{Title: "test"}); err != nil {
+		t.Fatalf("failed to send voting: %v", err)
+	}
+
+	block, err := n.MineBlock()
+	if err != nil {
+		t.Fatalf("failed to mine block: %v", err)
+	}
+
+	return block
+}
+
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(waitTimeout)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting to %s", what)
+		}
+		time.Sleep(time.Millisecond * 10)
+	}
+}
+
+func lastBlock(n *Node) blockchain.Block {
+	n.chainLock.Lock()
+	defer n.chainLock.Unlock()
+
+	return n.Chain.GetLastBlock()
+}
+
+func waitForTip(t *testing.T, n *Node, tip blockchain.Block) {
+	t.Helper()
+
+	waitFor(t, fmt.Sprintf("%s to reach block #%d", n.Name, tip.Nonce), func() bool {
+		return lastBlock(n).BlockHash == tip.BlockHash
+	})
+}
+
+func TestNodeSyncsChainOfPeer(t *testing.T) {
+	network := p2p.NewLocalNetwork()
+	a := newTestNode(t, network, "a")
+
+	var tip blockchain.Block
+	for range 3 {
+		tip = mineVoting(t, a)
+	}
+
+	b := newTestNode(t, network, "b")
+	connect(t, b, "a")
+
+	waitForTip(t, b, tip)
+}
+
+func TestNodeRelaysBroadcastBlock(t *testing.T) {
+	network := p2p.NewLocalNetwork()
+	a := newTestNode(t, network, "a")
+	b := newTestNode(t, network, "b")
+	c := newTestNode(t, network, "c")
+
+	// b is out of groups and reaches both, so blocks of a get to c
+	// only through b
+	network.Partition([]string{"a"}, []string{"c"})
+	connect(t, b, "a")
+	connect(t, c, "b")
+	waitFor(t, "connect peers", func() bool {
+		return len(b.PeerAddrs()) == 2
+	})
+
+	tip := mineVoting(t, a)
+
+	waitForTip(t, b, tip)
+	waitForTip(t, c, tip)
+}
+
+func TestNodeCatchesUpAfterPartition(t *testing.T) {
+	network := p2p.NewLocalNetwork()
+	a := newTestNode(t, network, "a")
+	b := newTestNode(t, network, "b")
+
+	connect(t, b, "a")
+	waitFor(t, "connect peers", func() bool {
+		return len(a.PeerAddrs()) == 1 && len(b.PeerAddrs()) == 1
+	})
+
+	network.Partition([]string{"a"}, []string{"b"})
+	for range 3 {
+		mineVoting(t, a)
+	}
+	if got := lastBlock(b).Nonce; got != 0 {
+		t.Fatalf("b got block #%d through partition", got)
+	}
+
+	network.Heal()
+	// next broadcast makes b download blocks it missed
+	tip := mineVoting(t, a)
+
+	waitForTip(t, b, tip)
+}
+
+// requestBlock asks peer of n for block with nonce.
+func requestBlock(n *Node, nonce int, timeout time.Duration) (blockchain.Block, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	var response GetBlockResponsePayload
+	err := n.Request(ctx, n.peerList()[0], GetBlock, GetBlockPayload{Nonce: nonce}, GetBlockResponse, &response)
+	return response.Block, err
+}
+
+func TestNodeServesRequestedBlock(t *testing.T) {
+	network := p2p.NewLocalNetwork()
+	a := newTestNode(t, network, "a")
+	first := mineVoting(t, a)
+	tip := mineVoting(t, a)
+
+	b := newTestNode(t, network, "b")
+	connect(t, b, "a")
+	waitForTip(t, b, tip)
+
+	tests := []struct {
+		name   string
+		nonce  int
+		expect string
+	}{
+		{name: "genesis", nonce: 0, expect: blockchain.GenesisBlock.BlockHash},
+		{name: "middle", nonce: 1, expect: first.BlockHash},
+		{name: "last", nonce: -1, expect: tip.BlockHash},
+		{name: "above tip", nonce: 100, expect: tip.BlockHash},
+		{name: "negative", nonce: -5},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			block, err := requestBlock(b, test.nonce, time.Millisecond*200)
+			if test.expect == "" {
+				if !errors.Is(err, context.DeadlineExceeded) {
+					t.Fatalf("expected no response, got block %s (err %v)", block.BlockHash, err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("failed to request block: %v", err)
+			}
+			if block.BlockHash != test.expect {
+				t.Fatalf("expected block %s, got %s", test.expect, block.BlockHash)
+			}
+		})
+	}
+}
+
+func TestNodeServesBlocksWhileMining(t *testing.T) {
+	network := p2p.NewLocalNetwork()
+	a := newTestNode(t, network, "a")
+
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	// blocks are read for peers while chain grows, -race reports reads of
+	// chain outside of its lock
+	go func() {
+		defer close(done)
+		for i := 0; ; i++ {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+
+			a.requestedBlock(i%4 - 1)
+		}
+	}()
+
+	for range 2 {
+		mineVoting(t, a)
+	}
+	close(stop)
+	<-done
+}
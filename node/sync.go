@@ -0,0 +1,263 @@
+package node
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+
+	"github.com/kotsmile/go-vote/blockchain"
+	"github.com/kotsmile/go-vote/p2p"
+)
+
+const (
+	// MaxParallelFetches is number of blocks requested from peer at once
+	// while catching up with it.
+	MaxParallelFetches = 8
+	// SyncBatch is number of blocks downloaded before they are added to
+	// chain.
+	SyncBatch = 128
+	// BlockWorkers is number of broadcast blocks synced at once.
+	BlockWorkers = 4
+	// MaxQueuedBlocks is number of broadcast blocks waiting for worker,
+	// blocks beyond it are dropped and caught up by next sync.
+	MaxQueuedBlocks = 256
+)
+
+var ErrBrokenBranch = errors.New("blocks of peer do not form branch")
+
+// queuedBlock is broadcast block waiting to be synced from peer which
+// sent it.
+type queuedBlock struct {
+	peer    p2p.Peer
+	payload BroadcastBlockPayload
+}
+
+// queueBlock hands broadcast block to block workers, false is returned
+// when queue is full.
+func (n *Node) queueBlock(peer p2p.Peer, payload BroadcastBlockPayload) bool {
+	select {
+	case n.blockCh <- queuedBlock{peer: peer, payload: payload}:
+		return true
+	default:
+		return false
+	}
+}
+
+// blockWorker syncs broadcast blocks, missing ancestors are downloaded
+// from peer, and relays blocks which extended chain.
+func (n *Node) blockWorker(verbose bool) {
+	for {
+		var queued queuedBlock
+		select {
+		case queued = <-n.blockCh:
+		case <-n.quitCh:
+			return
+		}
+
+		block := queued.payload.Block
+		res, err := n.syncBlock(queued.peer, block)
+		if err != nil || res.Status == blockchain.BlockSide {
+			continue
+		}
+
+		if verbose {
+			n.Log(block.String())
+		}
+
+		if err := n.BroadcastExcept(BroadcastBlock, queued.payload, queued.peer.Addr()); err != nil {
+			n.Log(fmt.Sprintf("failed to broadcast block: %v", err))
+		}
+	}
+}
+
+// syncPeer requests last block of peer and downloads blocks node misses.
+func (n *Node) syncPeer(peer p2p.Peer) {
+	block, err := n.fetchBlock(peer, -1)
+	if errors.Is(err, p2p.ErrPeerClosed) {
+		return
+	}
+	if err != nil {
+		n.Log(fmt.Sprintf("failed to get last block of %s: %v", peer.Addr(), err))
+		return
+	}
+
+	n.syncBlock(peer, block)
+}
+
+// syncBlock adds block received from peer, unknown ancestors of block are
+// downloaded from peer and added first.
+func (n *Node) syncBlock(peer p2p.Peer, block blockchain.Block) (blockchain.AddResult, error) {
+	res, err := n.addBlock(block)
+
+	if errors.Is(err, blockchain.ErrUnknownParent) {
+		// one download from peer at a time, next sync continues where
+		// it stops
+		if _, busy := n.syncing.LoadOrStore(peer.Addr(), struct{}{}); busy {
+			return res, err
+		}
+		defer n.syncing.Delete(peer.Addr())
+	}
+
+	for errors.Is(err, blockchain.ErrUnknownParent) {
+		ancestors, fetchErr := n.fetchAncestors(peer, block)
+		if fetchErr != nil {
+			n.Log(fmt.Sprintf("failed to download ancestors of block %s from %s: %v", block.BlockHash, peer.Addr(), fetchErr))
+			return res, err
+		}
+
+		for _, ancestor := range ancestors {
+			if _, addErr := n.addBlock(ancestor); addErr != nil && !errors.Is(addErr, blockchain.ErrBlockIncluded) {
+				n.Log(fmt.Sprintf("failed to add block %s: %v", ancestor.BlockHash, addErr))
+				return res, addErr
+			}
+		}
+
+		res, err = n.addBlock(block)
+	}
+
+	if err != nil && !errors.Is(err, blockchain.ErrBlockIncluded) {
+		n.Log(fmt.Sprintf("failed to add block %s: %v", block.BlockHash, err))
+	}
+
+	return res, err
+}
+
+// fetchAncestors downloads blocks of peer between chain of node and
+// block, oldest first. Blocks above tip of node are fetched in parallel,
+// at most SyncBatch of them, then branch of peer is walked back until its
+// parent is known.
+func (n *Node) fetchAncestors(peer p2p.Peer, block blockchain.Block) ([]blockchain.Block, error) {
+	n.chainLock.Lock()
+	lastNonce := n.Chain.GetLastBlock().Nonce
+	n.chainLock.Unlock()
+
+	ancestors := make([]blockchain.Block, 0)
+	if block.Nonce > lastNonce+1 {
+		to := min(block.Nonce-1, lastNonce+SyncBatch)
+
+		var err error
+		ancestors, err = n.fetchBlocks(peer, lastNonce+1, to)
+		if err != nil {
+			return nil, err
+		}
+
+		if to == block.Nonce-1 && ancestors[len(ancestors)-1].BlockHash != block.PrevBlockHash {
+			return nil, ErrBrokenBranch
+		}
+	}
+
+	first := block
+	if len(ancestors) > 0 {
+		first = ancestors[0]
+	}
+
+	for !n.hasBlock(first.PrevBlockHash) {
+		if first.Nonce == 0 {
+			return nil, fmt.Errorf("genesis block %s: %w", first.BlockHash, blockchain.ErrUnknownParent)
+		}
+		if first.Nonce+blockchain.MaxSideDepth <= lastNonce {
+			return nil, blockchain.ErrStaleBlock
+		}
+
+		parent, err := n.fetchBlock(peer, int(first.Nonce-1))
+		if err != nil {
+			return nil, err
+		}
+		if parent.BlockHash != first.PrevBlockHash {
+			return nil, ErrBrokenBranch
+		}
+
+		ancestors = slices.Insert(ancestors, 0, parent)
+		first = parent
+	}
+
+	return ancestors, nil
+}
+
+// fetchBlocks downloads blocks of peer with nonces from..to, at most
+// MaxParallelFetches requests are in flight.
+func (n *Node) fetchBlocks(peer p2p.Peer, from, to uint64) ([]blockchain.Block, error) {
+	blocks := make([]blockchain.Block, to-from+1)
+	errs := make([]error, len(blocks))
+
+	limit := make(chan struct{}, MaxParallelFetches)
+	var wg sync.WaitGroup
+	for i := range blocks {
+		limit <- struct{}{}
+		wg.Add(1)
+		go func() {
+			defer func() {
+				<-limit
+				wg.Done()
+			}()
+
+			blocks[i], errs[i] = n.fetchBlock(peer, int(from)+i)
+		}()
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for i := 1; i < len(blocks); i++ {
+		if blocks[i].PrevBlockHash != blocks[i-1].BlockHash {
+			return nil, ErrBrokenBranch
+		}
+	}
+
+	return blocks, nil
+}
+
+// fetchBlock requests block of peer with nonce, -1 stands for its last
+// block.
+func (n *Node) fetchBlock(peer p2p.Peer, nonce int) (blockchain.Block, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), p2p.DefaultRequestTimeout)
+	defer cancel()
+
+	var response GetBlockResponsePayload
+	if err := n.Request(ctx, peer, GetBlock, GetBlockPayload{Nonce: nonce}, GetBlockResponse, &response); err != nil {
+		return blockchain.Block{}, err
+	}
+
+	// peer answers with its last block when it has no block with
+	// requested nonce
+	if nonce != -1 && response.Block.Nonce != uint64(nonce) {
+		return blockchain.Block{}, fmt.Errorf("%w: peer has no block #%d", ErrUnexpectedResponse, nonce)
+	}
+
+	return response.Block, nil
+}
+
+// discoverPeers connects to peers of peer which node does not know.
+func (n *Node) discoverPeers(peer p2p.Peer) {
+	ctx, cancel := context.WithTimeout(context.Background(), p2p.DefaultRequestTimeout)
+	defer cancel()
+
+	var response GetPeersResponsePayload
+	if err := n.Request(ctx, peer, GetPeers, GetPeersPayload{}, GetPeersResponse, &response); err != nil {
+		n.Log(fmt.Sprintf("failed to get peers of %s: %v", peer.Addr(), err))
+		return
+	}
+
+	for _, peerAddr := range response.Peers {
+		if n.knowsPeer(peerAddr) {
+			continue
+		}
+
+		if err := n.Connect(peerAddr); err != nil {
+			n.Log(fmt.Sprintf("failed to connect %s: %v", peerAddr, err))
+		}
+	}
+}
+
+func (n *Node) hasBlock(blockHash string) bool {
+	n.chainLock.Lock()
+	defer n.chainLock.Unlock()
+
+	return n.Chain.HasBlock(blockHash)
+}
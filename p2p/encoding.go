@@ -6,24 +6,60 @@ import (
 	"io"
 )
 
-type Encoder struct{}
+// MessageType tells what message frame carries, so codec can check that
+// peer sends what is expected at current stage of connection.
+type MessageType uint8
 
-func (Encoder) Decode(r io.Reader, data any) error {
-	decoder := json.NewDecoder(r)
+const (
+	HandshakeMessage MessageType = iota + 1
+	RpcMessage
+)
 
-	if err := decoder.Decode(data); err != nil {
-		return fmt.Errorf("failed to decode: %v", err)
-	}
+// Codec writes and reads messages on connection. Codec is created per
+// connection and keeps data read ahead of current message.
+type Codec interface {
+	Encode(t MessageType, data any) error
+	Decode(t MessageType, data any) error
+}
 
-	return nil
+// CodecFunc creates codec on connection.
+type CodecFunc func(rw io.ReadWriter) Codec
+
+// JsonCodec sends messages as json values one after another. Values are
+// not limited in size, so it must be used only with trusted peers.
+type JsonCodec struct {
+	w       io.Writer
+	decoder *json.Decoder
 }
 
-func (Encoder) Encode(w io.Writer, data any) error {
-	encoder := json.NewEncoder(w)
+var _ Codec = (*JsonCodec)(nil)
+
+func NewJsonCodec(rw io.ReadWriter) Codec {
+	return &JsonCodec{
+		w:       rw,
+		decoder: json.NewDecoder(rw),
+	}
+}
 
-	if err := encoder.Encode(data); err != nil {
+func (c *JsonCodec) Encode(_ MessageType, data any) error {
+	body, err := json.Marshal(data)
+	if err != nil {
 		return fmt.Errorf("failed to encode data %+v: %v", data, err)
 	}
 
+	// value is written at once, so messages of concurrent senders do not
+	// interleave
+	if _, err := c.w.Write(append(body, '\n')); err != nil {
+		return fmt.Errorf("failed to write data: %v", err)
+	}
+
+	return nil
+}
+
+func (c *JsonCodec) Decode(_ MessageType, data any) error {
+	if err := c.decoder.Decode(data); err != nil {
+		return fmt.Errorf("failed to decode: %v", err)
+	}
+
 	return nil
 }
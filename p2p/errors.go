@@ -2,4 +2,7 @@ package p2p
 
 import "errors"
 
-var ErrTransportNotFound = errors.New("transport not found")
+var (
+	ErrTransportNotFound = errors.New("transport not found")
+	ErrTransportClosed   = errors.New("transport is closed")
+)
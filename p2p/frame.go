@@ -0,0 +1,239 @@
+package p2p
+
+import (
+	"bufio"
+	"bytes"
+	"compress/flate"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Frame is header followed by body:
+//
+//	| size uint32 | version uint8 | type uint8 | flags uint8 | body |
+//
+// Size is length of body, which is checked before body is read. Rpc
+// bodies are binary, other messages are json.
+const (
+	FrameVersion = 1
+
+	DefaultMaxFrameSize = 4 << 20
+
+	frameHeaderSize = 7
+	// bodies shorter than compressThreshold are sent uncompressed
+	compressThreshold = 1024
+)
+
+const (
+	frameCompressed uint8 = 1 << iota
+)
+
+var (
+	ErrFrameTooLarge      = errors.New("frame exceeds max size")
+	ErrUnsupportedFrame   = errors.New("unsupported frame")
+	ErrUnexpectedMessage  = errors.New("unexpected message type")
+	ErrInvalidRpcEncoding = errors.New("invalid rpc encoding")
+)
+
+// FramedCodec sends every message as length prefixed frame, so peer can
+// not make node read more than max frame size for single message.
+type FramedCodec struct {
+	r            *bufio.Reader
+	w            io.Writer
+	maxFrameSize int
+	compress     bool
+}
+
+var _ Codec = (*FramedCodec)(nil)
+
+// NewFramedCodecFunc returns codec which drops frames larger than
+// maxFrameSize and compresses large bodies when compress is set. Bodies
+// of peer are decompressed whatever compress is.
+func NewFramedCodecFunc(maxFrameSize int, compress bool) CodecFunc {
+	return func(rw io.ReadWriter) Codec {
+		return &FramedCodec{
+			r:            bufio.NewReader(rw),
+			w:            rw,
+			maxFrameSize: maxFrameSize,
+			compress:     compress,
+		}
+	}
+}
+
+func (c *FramedCodec) Encode(t MessageType, data any) error {
+	body, err := marshalMessage(data)
+	if err != nil {
+		return fmt.Errorf("failed to encode data %+v: %v", data, err)
+	}
+
+	// peer refuses bodies decompressed above max size, so size is checked
+	// before compression
+	if len(body) > c.maxFrameSize {
+		return fmt.Errorf("%w: %d bytes", ErrFrameTooLarge, len(body))
+	}
+
+	var flags uint8
+	if c.compress && len(body) >= compressThreshold {
+		compressed, err := deflate(body)
+		if err != nil {
+			return fmt.Errorf("failed to compress data: %v", err)
+		}
+		// incompressible bodies grow, they are sent as is
+		if len(compressed) < len(body) {
+			body = compressed
+			flags |= frameCompressed
+		}
+	}
+
+	frame := make([]byte, frameHeaderSize+len(body))
+	binary.BigEndian.PutUint32(frame, uint32(len(body)))
+	frame[4] = FrameVersion
+	frame[5] = uint8(t)
+	frame[6] = flags
+	copy(frame[frameHeaderSize:], body)
+
+	// frame is written at once, so frames of concurrent senders do not
+	// interleave
+	if _, err := c.w.Write(frame); err != nil {
+		return fmt.Errorf("failed to write frame: %v", err)
+	}
+
+	return nil
+}
+
+func (c *FramedCodec) Decode(t MessageType, data any) error {
+	header := make([]byte, frameHeaderSize)
+	if _, err := io.ReadFull(c.r, header); err != nil {
+		return fmt.Errorf("failed to read frame header: %w", err)
+	}
+
+	size := binary.BigEndian.Uint32(header)
+	version, messageType, flags := header[4], MessageType(header[5]), header[6]
+
+	if version != FrameVersion {
+		return fmt.Errorf("%w: version %d", ErrUnsupportedFrame, version)
+	}
+	if flags&^frameCompressed != 0 {
+		return fmt.Errorf("%w: flags %b", ErrUnsupportedFrame, flags)
+	}
+	if uint64(size) > uint64(c.maxFrameSize) {
+		return fmt.Errorf("%w: %d bytes", ErrFrameTooLarge, size)
+	}
+	if messageType != t {
+		return fmt.Errorf("%w %d, expected %d", ErrUnexpectedMessage, messageType, t)
+	}
+
+	body := make([]byte, size)
+	if _, err := io.ReadFull(c.r, body); err != nil {
+		return fmt.Errorf("failed to read frame body: %w", err)
+	}
+
+	if flags&frameCompressed != 0 {
+		var err error
+		body, err = inflate(body, c.maxFrameSize)
+		if err != nil {
+			return err
+		}
+	}
+
+	if err := unmarshalMessage(body, data); err != nil {
+		return fmt.Errorf("failed to decode: %v", err)
+	}
+
+	return nil
+}
+
+func deflate(body []byte) ([]byte, error) {
+	var buf bytes.Buffer
+
+	w, err := flate.NewWriter(&buf, flate.DefaultCompression)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(body); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// inflate decompresses body, which must not grow above maxSize.
+func inflate(body []byte, maxSize int) ([]byte, error) {
+	r := flate.NewReader(bytes.NewReader(body))
+	defer r.Close()
+
+	data, err := io.ReadAll(io.LimitReader(r, int64(maxSize)+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress frame: %v", err)
+	}
+	if len(data) > maxSize {
+		return nil, fmt.Errorf("%w: decompressed above %d bytes", ErrFrameTooLarge, maxSize)
+	}
+
+	return data, nil
+}
+
+func marshalMessage(data any) ([]byte, error) {
+	switch rpc := data.(type) {
+	case Rpc:
+		return marshalRpc(rpc)
+	case *Rpc:
+		return marshalRpc(*rpc)
+	default:
+		return json.Marshal(data)
+	}
+}
+
+func unmarshalMessage(body []byte, data any) error {
+	if rpc, ok := data.(*Rpc); ok {
+		return unmarshalRpc(body, rpc)
+	}
+
+	return json.Unmarshal(body, data)
+}
+
+// marshalRpc encodes rpc as
+//
+//	| id uint64 | replyTo uint64 | method length uint16 | method | payload |
+//
+// From is set by receiver, so it is not sent.
+func marshalRpc(rpc Rpc) ([]byte, error) {
+	if len(rpc.Method) > 0xffff {
+		return nil, fmt.Errorf("%w: method is too long", ErrInvalidRpcEncoding)
+	}
+
+	body := make([]byte, 0, 18+len(rpc.Method)+len(rpc.Payload))
+	body = binary.BigEndian.AppendUint64(body, rpc.ID)
+	body = binary.BigEndian.AppendUint64(body, rpc.ReplyTo)
+	body = binary.BigEndian.AppendUint16(body, uint16(len(rpc.Method)))
+	body = append(body, rpc.Method...)
+	body = append(body, rpc.Payload...)
+
+	return body, nil
+}
+
+func unmarshalRpc(body []byte, rpc *Rpc) error {
+	if len(body) < 18 {
+		return fmt.Errorf("%w: body is too short", ErrInvalidRpcEncoding)
+	}
+
+	methodLength := int(binary.BigEndian.Uint16(body[16:]))
+	if len(body) < 18+methodLength {
+		return fmt.Errorf("%w: method is truncated", ErrInvalidRpcEncoding)
+	}
+
+	*rpc = Rpc{
+		ID:      binary.BigEndian.Uint64(body),
+		ReplyTo: binary.BigEndian.Uint64(body[8:]),
+		Method:  RpcMethod(body[18 : 18+methodLength]),
+		Payload: body[18+methodLength:],
+	}
+
+	return nil
+}
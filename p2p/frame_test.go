@@ -0,0 +1,158 @@
+package p2p
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"testing"
+)
+
+const testMaxFrameSize = 64 << 10
+
+// newTestFrame returns raw frame with header fields as given, size is
+// taken from body.
+func newTestFrame(version uint8, t MessageType, flags uint8, body []byte) []byte {
+	frame := binary.BigEndian.AppendUint32(nil, uint32(len(body)))
+	frame = append(frame, version, uint8(t), flags)
+	return append(frame, body...)
+}
+
+func randomTestPayload(t *testing.T, size int) []byte {
+	t.Helper()
+
+	payload := make([]byte, size)
+	if _, err := rand.Read(payload); err != nil {
+		t.Fatalf("failed to read random bytes: %v", err)
+	}
+
+	return payload
+}
+
+func newTestCodec(buf *bytes.Buffer, compress bool) Codec {
+	return NewFramedCodecFunc(testMaxFrameSize, compress)(buf)
+}
+
+func TestFramedCodecRoundTrip(t *testing.T) {
+	tests := []struct {
+		name     string
+		compress bool
+		payload  []byte
+		// compressed tells whether frame on wire is compressed
+		compressed bool
+	}{
+		{name: "small rpc", payload: []byte("payload")},
+		{name: "small rpc with compression", compress: true, payload: []byte("payload")},
+		{name: "large rpc", payload: bytes.Repeat([]byte("a"), compressThreshold)},
+		{
+			name:     "incompressible rpc of max size",
+			compress: true,
+			payload:  randomTestPayload(t, testMaxFrameSize-18-len("method")),
+		},
+		{
+			name:       "large rpc with compression",
+			compress:   true,
+			payload:    bytes.Repeat([]byte("a"), compressThreshold),
+			compressed: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			codec := newTestCodec(&buf, test.compress)
+
+			sent := Rpc{ID: 1, ReplyTo: 2, Method: "method", Payload: test.payload}
+			if err := codec.Encode(RpcMessage, sent); err != nil {
+				t.Fatalf("failed to encode: %v", err)
+			}
+			if compressed := buf.Bytes()[6]&frameCompressed != 0; compressed != test.compressed {
+				t.Fatalf("expected compressed frame %v, got %v", test.compressed, compressed)
+			}
+
+			var received Rpc
+			if err := codec.Decode(RpcMessage, &received); err != nil {
+				t.Fatalf("failed to decode: %v", err)
+			}
+			if received.ID != sent.ID || received.ReplyTo != sent.ReplyTo || received.Method != sent.Method || !bytes.Equal(received.Payload, sent.Payload) {
+				t.Fatalf("expected %+v, got %+v", sent, received)
+			}
+		})
+	}
+}
+
+func TestFramedCodecRejectsFrame(t *testing.T) {
+	bomb, err := deflate(make([]byte, 16*testMaxFrameSize))
+	if err != nil {
+		t.Fatalf("failed to compress: %v", err)
+	}
+
+	tests := []struct {
+		name  string
+		frame []byte
+		err   error
+	}{
+		{
+			name: "size above max",
+			// body is not sent, so codec must fail before reading it
+			frame: newTestFrame(FrameVersion, RpcMessage, 0, make([]byte, testMaxFrameSize+1))[:frameHeaderSize],
+			err:   ErrFrameTooLarge,
+		},
+		{
+			name:  "decompression bomb",
+			frame: newTestFrame(FrameVersion, RpcMessage, frameCompressed, bomb),
+			err:   ErrFrameTooLarge,
+		},
+		{
+			name:  "unknown version",
+			frame: newTestFrame(FrameVersion+1, RpcMessage, 0, []byte("body")),
+			err:   ErrUnsupportedFrame,
+		},
+		{
+			name:  "unknown flags",
+			frame: newTestFrame(FrameVersion, RpcMessage, frameCompressed<<1, []byte("body")),
+			err:   ErrUnsupportedFrame,
+		},
+		{
+			name:  "unexpected message type",
+			frame: newTestFrame(FrameVersion, HandshakeMessage, 0, []byte("{}")),
+			err:   ErrUnexpectedMessage,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var rpc Rpc
+			err := newTestCodec(bytes.NewBuffer(test.frame), false).Decode(RpcMessage, &rpc)
+			if !errors.Is(err, test.err) {
+				t.Fatalf("expected %v, got %v", test.err, err)
+			}
+		})
+	}
+}
+
+func TestFramedCodecRefusesToSendLargeFrame(t *testing.T) {
+	tests := []struct {
+		name     string
+		compress bool
+	}{
+		{name: "uncompressed"},
+		// peer would refuse body decompressed above max size
+		{name: "compressed below max size", compress: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			codec := newTestCodec(&buf, test.compress)
+
+			err := codec.Encode(RpcMessage, Rpc{Payload: make([]byte, testMaxFrameSize)})
+			if !errors.Is(err, ErrFrameTooLarge) {
+				t.Fatalf("expected %v, got %v", ErrFrameTooLarge, err)
+			}
+			if buf.Len() != 0 {
+				t.Fatalf("large frame is written")
+			}
+		})
+	}
+}
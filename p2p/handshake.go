@@ -1,6 +1,182 @@
 package p2p
 
-// HandshakeFunc... ?
-type HandshakeFunc func(Peer) error
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+)
 
-func NOPHandshakeFunc(Peer) error { return nil }
+// ProtocolVersion is version of messages exchanged by peers, peers of
+// other versions are rejected during handshake. Version 2 added request
+// ids to rpcs.
+const ProtocolVersion = 2
+
+const HandshakeTimeout = time.Second * 10
+
+var (
+	ErrIncompatibleVersion = errors.New("incompatible protocol version")
+	ErrOtherNetwork        = errors.New("peer is from other network")
+	ErrInvalidIdentity     = errors.New("peer failed to prove its identity")
+	ErrSelfConnection      = errors.New("connection to self")
+)
+
+// PeerInfo describes node on the other side of connection, it is agreed
+// during handshake.
+type PeerInfo struct {
+	Version uint32 `json:"version"`
+	// Network is genesis hash of chain of node
+	Network    string `json:"network"`
+	ListenAddr string `json:"listenAddr"`
+	Height     uint64 `json:"height"`
+	// ID is public key of node which signed handshake
+	ID string `json:"id"`
+}
+
+// Identity is node key used to prove that node owns its ID.
+type Identity interface {
+	ID() string
+	Sign(data []byte) (string, error)
+	Verify(id string, data []byte, signature string) bool
+	// KeyID returns ID of node owning public key
+	KeyID(key crypto.PublicKey) (string, error)
+}
+
+// HandshakeConn sends and receives handshake messages on connection
+// before any Rpc.
+type HandshakeConn interface {
+	Send(data any) error
+	Receive(data any) error
+	// PeerKey returns key which peer proved to own while securing
+	// connection, it is nil for plain connections
+	PeerKey() crypto.PublicKey
+}
+
+// HandshakeFunc is run on both sides of new connection, connection is
+// dropped when it fails.
+type HandshakeFunc func(conn HandshakeConn) (PeerInfo, error)
+
+func NOPHandshakeFunc(HandshakeConn) (PeerInfo, error) { return PeerInfo{}, nil }
+
+type helloMessage struct {
+	PeerInfo
+	// Challenge is random data which other side must sign
+	Challenge string `json:"challenge"`
+}
+
+type proofMessage struct {
+	Signature string `json:"signature"`
+}
+
+// NewHandshakeFunc returns handshake in which both sides send their info
+// with random challenge and then sign challenge of other side together
+// with their info. Info of local node is taken on every connection, so
+// height is fresh.
+func NewHandshakeFunc(identity Identity, local func() PeerInfo) HandshakeFunc {
+	return func(conn HandshakeConn) (PeerInfo, error) {
+		challenge := make([]byte, 32)
+		if _, err := rand.Read(challenge); err != nil {
+			return PeerInfo{}, fmt.Errorf("failed to generate challenge: %v", err)
+		}
+
+		info := local()
+		info.Version = ProtocolVersion
+		info.ID = identity.ID()
+
+		hello := helloMessage{
+			PeerInfo:  info,
+			Challenge: hex.EncodeToString(challenge),
+		}
+		if err := conn.Send(hello); err != nil {
+			return PeerInfo{}, fmt.Errorf("failed to send hello: %v", err)
+		}
+
+		var remote helloMessage
+		if err := conn.Receive(&remote); err != nil {
+			return PeerInfo{}, fmt.Errorf("failed to receive hello: %v", err)
+		}
+
+		if remote.Version != ProtocolVersion {
+			return PeerInfo{}, fmt.Errorf("%w %d", ErrIncompatibleVersion, remote.Version)
+		}
+		if remote.Network != info.Network {
+			return PeerInfo{}, fmt.Errorf("%w %s", ErrOtherNetwork, remote.Network)
+		}
+		if remote.ID == info.ID {
+			return PeerInfo{}, ErrSelfConnection
+		}
+		if key := conn.PeerKey(); key != nil {
+			id, err := identity.KeyID(key)
+			if err != nil || id != remote.ID {
+				return PeerInfo{}, fmt.Errorf("%w: key of connection does not match id", ErrInvalidIdentity)
+			}
+		}
+
+		proof, err := proofData(info, remote.Challenge)
+		if err != nil {
+			return PeerInfo{}, err
+		}
+		signature, err := identity.Sign(proof)
+		if err != nil {
+			return PeerInfo{}, fmt.Errorf("failed to sign handshake: %v", err)
+		}
+		if err := conn.Send(proofMessage{Signature: signature}); err != nil {
+			return PeerInfo{}, fmt.Errorf("failed to send proof: %v", err)
+		}
+
+		var remoteProof proofMessage
+		if err := conn.Receive(&remoteProof); err != nil {
+			return PeerInfo{}, fmt.Errorf("failed to receive proof: %v", err)
+		}
+
+		expected, err := proofData(remote.PeerInfo, hello.Challenge)
+		if err != nil {
+			return PeerInfo{}, err
+		}
+		if !identity.Verify(remote.ID, expected, remoteProof.Signature) {
+			return PeerInfo{}, ErrInvalidIdentity
+		}
+
+		return remote.PeerInfo, nil
+	}
+}
+
+// proofData returns hash which node signs to prove it owns key of info.
+func proofData(info PeerInfo, challenge string) ([]byte, error) {
+	data, err := json.Marshal(struct {
+		PeerInfo
+		Challenge string `json:"challenge"`
+	}{info, challenge})
+	if err != nil {
+		return nil, fmt.Errorf("failed to serialize handshake: %v", err)
+	}
+
+	hash := sha256.Sum256(data)
+	return hash[:], nil
+}
+
+// DialAddr returns address on which peer accepts connections, host is
+// taken from remote address of connection when listen address has none.
+func DialAddr(peer Peer) string {
+	listenAddr := peer.Info().ListenAddr
+	if listenAddr == "" {
+		return peer.Addr()
+	}
+
+	host, port, err := net.SplitHostPort(listenAddr)
+	if err != nil || host != "" {
+		return listenAddr
+	}
+
+	remoteHost, _, err := net.SplitHostPort(peer.Addr())
+	if err != nil {
+		return listenAddr
+	}
+
+	return net.JoinHostPort(remoteHost, port)
+}
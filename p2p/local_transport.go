@@ -0,0 +1,641 @@
+package p2p
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var (
+	ErrUnreachable      = errors.New("transport is unreachable")
+	ErrAlreadyConnected = errors.New("already connected")
+)
+
+// LatencyFunc returns delay of message sent between transports, it is
+// counted on clock of network.
+type LatencyFunc func(from, to string) time.Duration
+
+// DropFunc reports whether message sent between transports is lost.
+type DropFunc func(from, to string, rpc Rpc) bool
+
+// ReorderFunc returns index of message which is delivered next out of
+// due messages waiting on link, it must not modify pending.
+type ReorderFunc func(from, to string, pending []Rpc) int
+
+// LocalNetwork connects local transports inside one process through
+// channels. Without hooks messages are delivered at once and in order, so
+// nodes can be tested without binding ports and sleeping. Hooks may be
+// changed while network runs.
+//
+// Network does not use wall clock: delayed messages arrive only when its
+// clock is moved by Advance. Hold keeps messages on links until test
+// delivers them one by one with Step, so delivery order is deterministic.
+type LocalNetwork struct {
+	lock       sync.RWMutex
+	transports map[string]*LocalTransport
+	// partitions holds group of address, transports of different groups
+	// can not reach each other
+	partitions map[string]int
+	// links are open sides of connections in order they were dialed
+	links []*LocalPeer
+
+	now  time.Duration
+	held bool
+
+	latency LatencyFunc
+	drop    DropFunc
+	reorder ReorderFunc
+}
+
+func NewLocalNetwork() *LocalNetwork {
+	return &LocalNetwork{
+		transports: make(map[string]*LocalTransport),
+		partitions: make(map[string]int),
+	}
+}
+
+// NewTransport returns transport of network with addr, it accepts
+// connections after ListenAndAccept.
+func (n *LocalNetwork) NewTransport(addr string) *LocalTransport {
+	return &LocalTransport{
+		network:   n,
+		addr:      addr,
+		rpcCh:     make(chan Rpc, 1024),
+		peers:     make(map[string]*LocalPeer),
+		Handshake: NOPHandshakeFunc,
+	}
+}
+
+func (n *LocalNetwork) SetLatency(latency LatencyFunc) {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+
+	n.latency = latency
+}
+
+func (n *LocalNetwork) SetDrop(drop DropFunc) {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+
+	n.drop = drop
+}
+
+func (n *LocalNetwork) SetReorder(reorder ReorderFunc) {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+
+	n.reorder = reorder
+}
+
+// Partition splits network into groups of addresses, messages between
+// groups are lost until Heal. Addresses out of groups reach everyone.
+func (n *LocalNetwork) Partition(groups ...[]string) {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+
+	clear(n.partitions)
+	for i, group := range groups {
+		for _, addr := range group {
+			n.partitions[addr] = i
+		}
+	}
+}
+
+func (n *LocalNetwork) Heal() {
+	n.Partition()
+}
+
+// Hold stops delivery of messages, they wait on links until Step, Flush
+// or Release.
+func (n *LocalNetwork) Hold() {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+
+	n.held = true
+}
+
+// Release resumes delivery of messages held since Hold.
+func (n *LocalNetwork) Release() {
+	n.lock.Lock()
+	n.held = false
+	n.lock.Unlock()
+
+	n.wake()
+}
+
+// Advance moves clock of network by d, messages whose delay has passed
+// become due.
+func (n *LocalNetwork) Advance(d time.Duration) {
+	n.lock.Lock()
+	n.now += d
+	n.lock.Unlock()
+
+	n.wake()
+}
+
+// Step delivers one due message, links are visited in order they were
+// dialed. False is returned when no message is due.
+func (n *LocalNetwork) Step() bool {
+	for _, link := range n.linkList() {
+		if link.deliverNext() {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Flush delivers due messages until none is left and returns their
+// number.
+func (n *LocalNetwork) Flush() int {
+	delivered := 0
+	for n.Step() {
+		delivered++
+	}
+
+	return delivered
+}
+
+// Pending returns number of messages waiting on links, including ones
+// which are not due yet.
+func (n *LocalNetwork) Pending() int {
+	pending := 0
+	for _, link := range n.linkList() {
+		link.lock.Lock()
+		pending += len(link.pending)
+		link.lock.Unlock()
+	}
+
+	return pending
+}
+
+func (n *LocalNetwork) clock() time.Duration {
+	n.lock.RLock()
+	defer n.lock.RUnlock()
+
+	return n.now
+}
+
+func (n *LocalNetwork) flowing() bool {
+	n.lock.RLock()
+	defer n.lock.RUnlock()
+
+	return !n.held
+}
+
+func (n *LocalNetwork) linkList() []*LocalPeer {
+	n.lock.RLock()
+	defer n.lock.RUnlock()
+
+	return slices.Clone(n.links)
+}
+
+func (n *LocalNetwork) addLink(links ...*LocalPeer) {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+
+	n.links = append(n.links, links...)
+}
+
+func (n *LocalNetwork) removeLink(link *LocalPeer) {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+
+	n.links = slices.DeleteFunc(n.links, func(other *LocalPeer) bool {
+		return other == link
+	})
+}
+
+// wake makes links deliver messages which became due.
+func (n *LocalNetwork) wake() {
+	for _, link := range n.linkList() {
+		link.wake()
+	}
+}
+
+func (n *LocalNetwork) reachable(from, to string) bool {
+	n.lock.RLock()
+	defer n.lock.RUnlock()
+
+	return n.reachableLocked(from, to)
+}
+
+func (n *LocalNetwork) reachableLocked(from, to string) bool {
+	fromGroup, fromOk := n.partitions[from]
+	toGroup, toOk := n.partitions[to]
+	return !fromOk || !toOk || fromGroup == toGroup
+}
+
+// route returns time of network clock when message arrives, message is
+// lost when ok is false.
+func (n *LocalNetwork) route(from, to string, rpc Rpc) (due time.Duration, ok bool) {
+	n.lock.RLock()
+	defer n.lock.RUnlock()
+
+	if !n.reachableLocked(from, to) {
+		return 0, false
+	}
+	if n.drop != nil && n.drop(from, to, rpc) {
+		return 0, false
+	}
+
+	due = n.now
+	if n.latency != nil {
+		due += max(n.latency(from, to), 0)
+	}
+
+	return due, true
+}
+
+func (n *LocalNetwork) next(from, to string, pending []Rpc) int {
+	n.lock.RLock()
+	reorder := n.reorder
+	n.lock.RUnlock()
+
+	if reorder == nil {
+		return 0
+	}
+
+	return min(max(reorder(from, to, pending), 0), len(pending)-1)
+}
+
+func (n *LocalNetwork) listener(addr string) (*LocalTransport, bool) {
+	n.lock.RLock()
+	defer n.lock.RUnlock()
+
+	t, ok := n.transports[addr]
+	return t, ok
+}
+
+type LocalTransport struct {
+	network *LocalNetwork
+	addr    string
+	rpcCh   chan Rpc
+
+	peersLock sync.Mutex
+	peers     map[string]*LocalPeer
+
+	OnPeer      func(Peer) error
+	OnPeerClose func(Peer)
+	Handshake   HandshakeFunc
+}
+
+var _ Transport = (*LocalTransport)(nil)
+
+func (t *LocalTransport) Addr() string {
+	return t.addr
+}
+
+func (t *LocalTransport) SetOnPeer(onPeer func(Peer) error) {
+	t.OnPeer = onPeer
+}
+
+func (t *LocalTransport) SetOnPeerClose(onPeerClose func(Peer)) {
+	t.OnPeerClose = onPeerClose
+}
+
+func (t *LocalTransport) SetHandshake(handshake HandshakeFunc) {
+	t.Handshake = handshake
+}
+
+func (t *LocalTransport) ListenAndAccept() error {
+	t.network.lock.Lock()
+	defer t.network.lock.Unlock()
+
+	if _, ok := t.network.transports[t.addr]; ok {
+		return fmt.Errorf("failed to listen on %s: address is taken", t.addr)
+	}
+	t.network.transports[t.addr] = t
+
+	return nil
+}
+
+func (t *LocalTransport) Close() error {
+	t.network.lock.Lock()
+	if t.network.transports[t.addr] == t {
+		delete(t.network.transports, t.addr)
+	}
+	t.network.lock.Unlock()
+
+	t.peersLock.Lock()
+	peers := make([]*LocalPeer, 0, len(t.peers))
+	for _, peer := range t.peers {
+		peers = append(peers, peer)
+	}
+	t.peersLock.Unlock()
+
+	for _, peer := range peers {
+		peer.Close()
+	}
+
+	return nil
+}
+
+func (t *LocalTransport) Consume() <-chan Rpc {
+	return t.rpcCh
+}
+
+// Dial connects transport to transport listening on addr. Unlike tcp
+// transport it returns after handshake and OnPeer of both sides passed.
+func (t *LocalTransport) Dial(addr string) error {
+	remote, ok := t.network.listener(addr)
+	if !ok {
+		return fmt.Errorf("failed to dial %s: %w", addr, ErrTransportNotFound)
+	}
+	if !t.network.reachable(t.addr, addr) {
+		return fmt.Errorf("failed to dial %s: %w", addr, ErrUnreachable)
+	}
+	if t.hasPeer(addr) || remote.hasPeer(t.addr) {
+		return fmt.Errorf("failed to dial %s: %w", addr, ErrAlreadyConnected)
+	}
+
+	outbound, inbound := newLocalPeer(t), newLocalPeer(remote)
+	outbound.remote, inbound.remote = inbound, outbound
+
+	if err := handshakeLocal(outbound, inbound); err != nil {
+		return fmt.Errorf("handshake with %s failed: %v", addr, err)
+	}
+
+	t.addPeer(outbound)
+	remote.addPeer(inbound)
+	t.network.addLink(outbound, inbound)
+
+	for _, peer := range []*LocalPeer{outbound, inbound} {
+		if peer.transport.OnPeer != nil {
+			if err := peer.transport.OnPeer(peer); err != nil {
+				outbound.Close()
+				return fmt.Errorf("failed to call 'onPeer': %v", err)
+			}
+		}
+		peer.accepted.Store(true)
+	}
+
+	go outbound.deliverLoop()
+	go inbound.deliverLoop()
+
+	return nil
+}
+
+func (t *LocalTransport) hasPeer(addr string) bool {
+	t.peersLock.Lock()
+	defer t.peersLock.Unlock()
+
+	_, ok := t.peers[addr]
+	return ok
+}
+
+func (t *LocalTransport) addPeer(peer *LocalPeer) {
+	t.peersLock.Lock()
+	defer t.peersLock.Unlock()
+
+	t.peers[peer.Addr()] = peer
+}
+
+func (t *LocalTransport) removePeer(peer *LocalPeer) {
+	t.peersLock.Lock()
+	defer t.peersLock.Unlock()
+
+	if t.peers[peer.Addr()] == peer {
+		delete(t.peers, peer.Addr())
+	}
+}
+
+// handshakeLocal runs handshakes of both sides of connection at once,
+// failure of one side stops the other.
+func handshakeLocal(outbound, inbound *LocalPeer) error {
+	ctx, cancel := context.WithTimeout(context.Background(), HandshakeTimeout)
+	defer cancel()
+
+	outCh, inCh := make(chan []byte, 1), make(chan []byte, 1)
+
+	var wg sync.WaitGroup
+	errs := make([]error, 2)
+	for i, side := range []struct {
+		peer *LocalPeer
+		conn localHandshakeConn
+	}{
+		{outbound, localHandshakeConn{ctx: ctx, sendCh: outCh, receiveCh: inCh}},
+		{inbound, localHandshakeConn{ctx: ctx, sendCh: inCh, receiveCh: outCh}},
+	} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			side.peer.info, errs[i] = side.peer.transport.Handshake(side.conn)
+			if errs[i] != nil {
+				cancel()
+			}
+		}()
+	}
+	wg.Wait()
+
+	return errors.Join(errs...)
+}
+
+type localHandshakeConn struct {
+	ctx       context.Context
+	sendCh    chan<- []byte
+	receiveCh <-chan []byte
+}
+
+func (c localHandshakeConn) PeerKey() crypto.PublicKey {
+	return nil
+}
+
+func (c localHandshakeConn) Send(data any) error {
+	message, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("failed to encode data %+v: %v", data, err)
+	}
+
+	select {
+	case c.sendCh <- message:
+		return nil
+	case <-c.ctx.Done():
+		return ErrPeerClosed
+	}
+}
+
+func (c localHandshakeConn) Receive(data any) error {
+	select {
+	case message := <-c.receiveCh:
+		if err := json.Unmarshal(message, data); err != nil {
+			return fmt.Errorf("failed to decode: %v", err)
+		}
+		return nil
+	case <-c.ctx.Done():
+		return ErrPeerClosed
+	}
+}
+
+// localMessage is rpc waiting on link.
+type localMessage struct {
+	rpc Rpc
+	// due is time of network clock when message arrives
+	due time.Duration
+}
+
+// LocalPeer is one side of local connection, messages sent to it wait in
+// its queue until they are delivered to remote side.
+type LocalPeer struct {
+	transport *LocalTransport
+	remote    *LocalPeer
+	info      PeerInfo
+	requests  *requests
+	// accepted is set once OnPeer of transport took peer, only such peers
+	// are reported to OnPeerClose
+	accepted atomic.Bool
+
+	lock    sync.Mutex
+	pending []localMessage
+	wakeCh  chan struct{}
+
+	closeOnce sync.Once
+	done      chan struct{}
+}
+
+var _ Peer = (*LocalPeer)(nil)
+
+func newLocalPeer(t *LocalTransport) *LocalPeer {
+	return &LocalPeer{
+		transport: t,
+		requests:  newRequests(),
+		pending:   make([]localMessage, 0),
+		wakeCh:    make(chan struct{}, 1),
+		done:      make(chan struct{}),
+	}
+}
+
+// Send queues rpc for delivery, lost messages are not reported like on
+// real network.
+func (p *LocalPeer) Send(rpc Rpc) error {
+	select {
+	case <-p.done:
+		return ErrPeerClosed
+	default:
+	}
+
+	// payload is copied as it would be by wire
+	rpc.Payload = bytes.Clone(rpc.Payload)
+
+	due, ok := p.transport.network.route(p.transport.addr, p.Addr(), rpc)
+	if !ok {
+		return nil
+	}
+
+	p.lock.Lock()
+	p.pending = append(p.pending, localMessage{rpc: rpc, due: due})
+	p.lock.Unlock()
+
+	p.wake()
+	return nil
+}
+
+func (p *LocalPeer) Request(ctx context.Context, method RpcMethod, payload []byte) (Rpc, error) {
+	return p.requests.do(ctx, p.Send, method, payload)
+}
+
+// Addr returns address of transport on the other side.
+func (p *LocalPeer) Addr() string {
+	return p.remote.transport.addr
+}
+
+func (p *LocalPeer) Info() PeerInfo {
+	return p.info
+}
+
+// Close closes both sides of connection.
+func (p *LocalPeer) Close() error {
+	p.close()
+	p.remote.close()
+	return nil
+}
+
+func (p *LocalPeer) close() {
+	p.closeOnce.Do(func() {
+		close(p.done)
+		p.requests.close()
+		p.transport.removePeer(p)
+		p.transport.network.removeLink(p)
+
+		if p.accepted.Load() && p.transport.OnPeerClose != nil {
+			p.transport.OnPeerClose(p)
+		}
+	})
+}
+
+func (p *LocalPeer) wake() {
+	select {
+	case p.wakeCh <- struct{}{}:
+	default:
+	}
+}
+
+// deliverLoop passes due messages to remote side while network is not
+// held.
+func (p *LocalPeer) deliverLoop() {
+	for {
+		select {
+		case <-p.wakeCh:
+		case <-p.done:
+			return
+		}
+
+		for p.transport.network.flowing() && p.deliverNext() {
+		}
+	}
+}
+
+// deliverNext passes one due message to remote side, picked by reorder
+// hook of network. False is returned when no message is due.
+func (p *LocalPeer) deliverNext() bool {
+	from, to := p.transport.addr, p.Addr()
+	now := p.transport.network.clock()
+
+	p.lock.Lock()
+	due := make([]int, 0, len(p.pending))
+	rpcs := make([]Rpc, 0, len(p.pending))
+	for i, message := range p.pending {
+		if message.due <= now {
+			due = append(due, i)
+			rpcs = append(rpcs, message.rpc)
+		}
+	}
+	if len(due) == 0 {
+		p.lock.Unlock()
+		return false
+	}
+
+	i := due[p.transport.network.next(from, to, rpcs)]
+	rpc := p.pending[i].rpc
+	p.pending = slices.Delete(p.pending, i, i+1)
+	p.lock.Unlock()
+
+	// partition may appear while message is on its way
+	if p.transport.network.reachable(from, to) {
+		p.remote.receive(rpc)
+	}
+
+	return true
+}
+
+// receive handles rpc which came from remote side.
+func (p *LocalPeer) receive(rpc Rpc) {
+	rpc.From = p.Addr()
+	if rpc.ReplyTo != 0 {
+		p.requests.deliver(rpc)
+		return
+	}
+
+	select {
+	case p.transport.rpcCh <- rpc:
+	case <-p.done:
+	}
+}
@@ -0,0 +1,195 @@
+package p2p
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+// newTestLink connects transports a and b of held network and returns
+// side of a sending to b.
+func newTestLink(t *testing.T) (*LocalNetwork, *LocalTransport, *LocalPeer) {
+	t.Helper()
+
+	network := NewLocalNetwork()
+	network.Hold()
+
+	a, b := network.NewTransport("a"), network.NewTransport("b")
+	if err := b.ListenAndAccept(); err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	if err := a.Dial("b"); err != nil {
+		t.Fatalf("failed to dial: %v", err)
+	}
+	t.Cleanup(func() {
+		a.Close()
+		b.Close()
+	})
+
+	return network, b, a.peers["b"]
+}
+
+func sendTestRpcs(t *testing.T, peer *LocalPeer, methods ...RpcMethod) {
+	t.Helper()
+
+	for _, method := range methods {
+		if err := peer.Send(Rpc{Method: method}); err != nil {
+			t.Fatalf("failed to send %s: %v", method, err)
+		}
+	}
+}
+
+// received returns methods of rpcs which reached transport so far.
+func received(t *LocalTransport) []RpcMethod {
+	methods := make([]RpcMethod, 0)
+	for {
+		select {
+		case rpc := <-t.Consume():
+			methods = append(methods, rpc.Method)
+		default:
+			return methods
+		}
+	}
+}
+
+func TestLocalNetworkHold(t *testing.T) {
+	network, b, peer := newTestLink(t)
+
+	sendTestRpcs(t, peer, "first", "second")
+	if got := received(b); len(got) != 0 {
+		t.Fatalf("held messages were delivered: %v", got)
+	}
+	if network.Pending() != 2 {
+		t.Fatalf("expected 2 pending messages, got %d", network.Pending())
+	}
+
+	if !network.Step() {
+		t.Fatalf("expected message to be delivered")
+	}
+	if got := fmt.Sprint(received(b)); got != "[first]" {
+		t.Fatalf("expected [first], got %s", got)
+	}
+
+	if delivered := network.Flush(); delivered != 1 {
+		t.Fatalf("expected 1 delivered message, got %d", delivered)
+	}
+	if network.Step() {
+		t.Fatalf("expected no message to be due")
+	}
+}
+
+func TestLocalNetworkReorder(t *testing.T) {
+	network, b, peer := newTestLink(t)
+
+	seen := make([]int, 0)
+	network.SetReorder(func(_, _ string, pending []Rpc) int {
+		seen = append(seen, len(pending))
+		return len(pending) - 1
+	})
+
+	sendTestRpcs(t, peer, "first", "second", "third")
+	network.Flush()
+
+	if got := fmt.Sprint(received(b)); got != "[third second first]" {
+		t.Fatalf("expected [third second first], got %s", got)
+	}
+	if got := fmt.Sprint(seen); got != "[3 2 1]" {
+		t.Fatalf("expected reorder to see [3 2 1] messages, got %s", got)
+	}
+}
+
+func TestLocalNetworkLatency(t *testing.T) {
+	network, b, peer := newTestLink(t)
+	network.SetLatency(func(_, _ string) time.Duration {
+		return time.Second
+	})
+
+	sendTestRpcs(t, peer, "delayed")
+
+	network.Advance(time.Second / 2)
+	if network.Step() {
+		t.Fatalf("message was delivered before its delay")
+	}
+
+	network.Advance(time.Second / 2)
+	if !network.Step() {
+		t.Fatalf("message was not delivered after its delay")
+	}
+	if got := fmt.Sprint(received(b)); got != "[delayed]" {
+		t.Fatalf("expected [delayed], got %s", got)
+	}
+}
+
+func TestLocalNetworkDrop(t *testing.T) {
+	network, b, peer := newTestLink(t)
+	network.SetDrop(func(_, _ string, rpc Rpc) bool {
+		return rpc.Method == "lost"
+	})
+
+	sendTestRpcs(t, peer, "lost", "kept")
+	network.Flush()
+
+	if got := fmt.Sprint(received(b)); got != "[kept]" {
+		t.Fatalf("expected [kept], got %s", got)
+	}
+}
+
+func TestLocalNetworkPartition(t *testing.T) {
+	network, b, peer := newTestLink(t)
+
+	sendTestRpcs(t, peer, "sent")
+	network.Partition([]string{"a", "c"}, []string{"b"})
+	sendTestRpcs(t, peer, "lost")
+
+	// message sent before partition is lost on its way
+	if delivered := network.Flush(); delivered != 1 {
+		t.Fatalf("expected 1 message to leave link, got %d", delivered)
+	}
+	if got := received(b); len(got) != 0 {
+		t.Fatalf("messages crossed partition: %v", got)
+	}
+
+	c := network.NewTransport("c")
+	if err := c.Dial("b"); !errors.Is(err, ErrUnreachable) {
+		t.Fatalf("expected %v, got %v", ErrUnreachable, err)
+	}
+
+	network.Heal()
+	sendTestRpcs(t, peer, "healed")
+	network.Flush()
+
+	if got := fmt.Sprint(received(b)); got != "[healed]" {
+		t.Fatalf("expected [healed], got %s", got)
+	}
+}
+
+func TestLocalTransportReportsClosedPeer(t *testing.T) {
+	network := NewLocalNetwork()
+	a, b := network.NewTransport("a"), network.NewTransport("b")
+
+	closed := make(chan string, 2)
+	for _, transport := range []*LocalTransport{a, b} {
+		transport.SetOnPeerClose(func(peer Peer) {
+			closed <- peer.Addr()
+		})
+	}
+
+	if err := b.ListenAndAccept(); err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	if err := a.Dial("b"); err != nil {
+		t.Fatalf("failed to dial: %v", err)
+	}
+
+	a.Close()
+
+	for _, expected := range []string{"b", "a"} {
+		if addr := <-closed; addr != expected {
+			t.Fatalf("expected closed peer %s, got %s", expected, addr)
+		}
+	}
+	if network.Pending() != 0 || len(network.linkList()) != 0 {
+		t.Fatalf("closed links are kept by network")
+	}
+}
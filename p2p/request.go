@@ -0,0 +1,85 @@
+package p2p
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+const DefaultRequestTimeout = time.Second * 10
+
+var ErrPeerClosed = errors.New("peer connection closed")
+
+// requests matches responses of peer to requests waiting for them.
+type requests struct {
+	lock    sync.Mutex
+	lastID  uint64
+	waiting map[uint64]chan Rpc
+	closed  bool
+}
+
+func newRequests() *requests {
+	return &requests{
+		waiting: make(map[uint64]chan Rpc),
+	}
+}
+
+// do sends request by send and waits for response until ctx is done.
+func (r *requests) do(ctx context.Context, send func(Rpc) error, method RpcMethod, payload []byte) (Rpc, error) {
+	r.lock.Lock()
+	if r.closed {
+		r.lock.Unlock()
+		return Rpc{}, ErrPeerClosed
+	}
+	r.lastID++
+	id := r.lastID
+	responseCh := make(chan Rpc, 1)
+	r.waiting[id] = responseCh
+	r.lock.Unlock()
+
+	defer func() {
+		r.lock.Lock()
+		delete(r.waiting, id)
+		r.lock.Unlock()
+	}()
+
+	if err := send(Rpc{ID: id, Method: method, Payload: payload}); err != nil {
+		return Rpc{}, err
+	}
+
+	select {
+	case response, ok := <-responseCh:
+		if !ok {
+			return Rpc{}, ErrPeerClosed
+		}
+		return response, nil
+	case <-ctx.Done():
+		return Rpc{}, fmt.Errorf("no response to %s #%d: %w", method, id, ctx.Err())
+	}
+}
+
+// deliver passes response to request waiting for it, response to request
+// which timed out is dropped.
+func (r *requests) deliver(response Rpc) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if responseCh, ok := r.waiting[response.ReplyTo]; ok {
+		responseCh <- response
+		delete(r.waiting, response.ReplyTo)
+	}
+}
+
+// close fails all waiting requests.
+func (r *requests) close() {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.closed = true
+	for id, responseCh := range r.waiting {
+		close(responseCh)
+		delete(r.waiting, id)
+	}
+}
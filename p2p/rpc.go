@@ -3,7 +3,20 @@ package p2p
 type RpcMethod string
 
 type Rpc struct {
-	From    string
+	From string
+	// ID identifies request, response refers to it by ReplyTo. Both are
+	// zero for messages which expect no response.
+	ID      uint64    `json:"id,omitempty"`
+	ReplyTo uint64    `json:"replyTo,omitempty"`
 	Method  RpcMethod `json:"method"`
 	Payload []byte    `json:"payload"`
 }
+
+// Reply returns response to rpc.
+func (rpc Rpc) Reply(method RpcMethod, payload []byte) Rpc {
+	return Rpc{
+		ReplyTo: rpc.ID,
+		Method:  method,
+		Payload: payload,
+	}
+}
@@ -0,0 +1,49 @@
+package p2p
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+)
+
+var ErrInvalidCertificate = errors.New("invalid peer certificate")
+
+// NewSecureTcpTransport returns transport whose connections are encrypted
+// by mutual TLS. Peers present self-signed certificates of their node
+// keys, so they are authenticated by public key instead of by CA, and
+// handshake checks that key belongs to node ID.
+func NewSecureTcpTransport(listenAddr string, cert tls.Certificate) *TcpTransport {
+	t := NewTcpTransport(listenAddr)
+	t.tlsConfig = NewTlsConfig(cert)
+	return t
+}
+
+func NewTlsConfig(cert tls.Certificate) *tls.Config {
+	return &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		ClientAuth:   tls.RequireAnyClientCert,
+		// there is no CA, certificate is checked by verifyPeerCertificate
+		// and its key is bound to node ID during handshake
+		InsecureSkipVerify:    true,
+		VerifyPeerCertificate: verifyPeerCertificate,
+		MinVersion:            tls.VersionTLS13,
+	}
+}
+
+// verifyPeerCertificate accepts single certificate signed by its own key.
+func verifyPeerCertificate(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+	if len(rawCerts) != 1 {
+		return fmt.Errorf("%w: expected single certificate, got %d", ErrInvalidCertificate, len(rawCerts))
+	}
+
+	cert, err := x509.ParseCertificate(rawCerts[0])
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidCertificate, err)
+	}
+	if err := cert.CheckSignature(cert.SignatureAlgorithm, cert.RawTBSCertificate, cert.Signature); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidCertificate, err)
+	}
+
+	return nil
+}
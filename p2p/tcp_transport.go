@@ -1,10 +1,14 @@
 package p2p
 
 import (
-	"encoding/json"
+	"context"
+	"crypto"
+	"crypto/tls"
 	"errors"
 	"fmt"
 	"net"
+	"sync"
+	"time"
 )
 
 type TcpTransport struct {
@@ -12,9 +16,20 @@ type TcpTransport struct {
 
 	rpcCh    chan Rpc
 	listener net.Listener
-	encoder  Encoder
-
-	OnPeer func(Peer) error
+	// tlsConfig encrypts connections when set
+	tlsConfig *tls.Config
+
+	// conns are accepted and dialed connections, closed with transport
+	connsLock sync.Mutex
+	conns     map[net.Conn]struct{}
+	closed    bool
+
+	OnPeer      func(Peer) error
+	OnPeerClose func(Peer)
+	Handshake   HandshakeFunc
+	// Codec creates codec of every connection, both sides must use the
+	// same codec
+	Codec CodecFunc
 }
 
 var _ Transport = (*TcpTransport)(nil)
@@ -23,7 +38,9 @@ func NewTcpTransport(listenAddr string) *TcpTransport {
 	return &TcpTransport{
 		listenAddr: listenAddr,
 		rpcCh:      make(chan Rpc, 1024),
-		encoder:    Encoder{},
+		conns:      make(map[net.Conn]struct{}),
+		Handshake:  NOPHandshakeFunc,
+		Codec:      NewFramedCodecFunc(DefaultMaxFrameSize, false),
 	}
 }
 
@@ -35,10 +52,30 @@ func (t *TcpTransport) SetOnPeer(onPeer func(Peer) error) {
 	t.OnPeer = onPeer
 }
 
+func (t *TcpTransport) SetOnPeerClose(onPeerClose func(Peer)) {
+	t.OnPeerClose = onPeerClose
+}
+
+func (t *TcpTransport) SetHandshake(handshake HandshakeFunc) {
+	t.Handshake = handshake
+}
+
+// Close stops accepting connections and closes connections of all peers.
 func (t *TcpTransport) Close() error {
+	t.connsLock.Lock()
+	t.closed = true
+	conns := make([]net.Conn, 0, len(t.conns))
+	for conn := range t.conns {
+		conns = append(conns, conn)
+	}
+	t.connsLock.Unlock()
+
 	if t.listener != nil {
 		t.listener.Close()
 	}
+	for _, conn := range conns {
+		conn.Close()
+	}
 
 	return nil
 }
@@ -48,12 +85,36 @@ func (t *TcpTransport) Dial(addr string) error {
 	if err != nil {
 		return fmt.Errorf("failed to dial %s: %v", addr, err)
 	}
+	if !t.addConn(conn) {
+		conn.Close()
+		return fmt.Errorf("failed to dial %s: %w", addr, ErrTransportClosed)
+	}
 
 	go t.handleConn(conn, true)
 
 	return nil
 }
 
+// addConn tracks conn until it is dropped, conn is not tracked once
+// transport is closed.
+func (t *TcpTransport) addConn(conn net.Conn) bool {
+	t.connsLock.Lock()
+	defer t.connsLock.Unlock()
+
+	if t.closed {
+		return false
+	}
+	t.conns[conn] = struct{}{}
+	return true
+}
+
+func (t *TcpTransport) removeConn(conn net.Conn) {
+	t.connsLock.Lock()
+	defer t.connsLock.Unlock()
+
+	delete(t.conns, conn)
+}
+
 func (t *TcpTransport) ListenAndAccept() error {
 	var err error
 
@@ -81,6 +142,10 @@ func (t *TcpTransport) acceptLoop() {
 			fmt.Printf("failed to accept conn on %s: %v\n", t.listenAddr, err)
 			continue
 		}
+		if !t.addConn(conn) {
+			conn.Close()
+			return
+		}
 
 		go t.handleConn(conn, false)
 	}
@@ -89,58 +154,139 @@ func (t *TcpTransport) acceptLoop() {
 func (t *TcpTransport) handleConn(conn net.Conn, outbound bool) {
 	var err error
 
+	defer t.removeConn(conn)
 	defer func() {
-		fmt.Printf("dropping peer connection: %s", err)
+		fmt.Printf("dropping peer connection: %s\n", err)
 		conn.Close()
 	}()
 
-	peer := NewTcpPeer(conn, outbound)
+	conn.SetDeadline(time.Now().Add(HandshakeTimeout))
+
+	var key crypto.PublicKey
+	if t.tlsConfig != nil {
+		conn, key, err = t.secure(conn, outbound)
+		if err != nil {
+			err = fmt.Errorf("tls handshake with %s failed: %v", conn.RemoteAddr(), err)
+			return
+		}
+	}
+
+	// codec must live as long as connection, otherwise data buffered
+	// after first message is lost
+	peer := NewTcpPeer(conn, outbound, t.Codec(conn))
+
+	peer.info, err = t.Handshake(tcpHandshakeConn{peer: peer, key: key})
+	if err != nil {
+		err = fmt.Errorf("handshake with %s failed: %v", peer.Addr(), err)
+		return
+	}
+	conn.SetDeadline(time.Time{})
+
 	if t.OnPeer != nil {
-		if err := t.OnPeer(peer); err != nil {
-			fmt.Printf("failed to call 'onPeer': %v", err)
+		if err = t.OnPeer(peer); err != nil {
+			err = fmt.Errorf("failed to call 'onPeer': %v", err)
 			return
 		}
 	}
 
+	defer peer.requests.close()
+	if t.OnPeerClose != nil {
+		defer t.OnPeerClose(peer)
+	}
+
 	for {
 		rpc := Rpc{}
-		err = t.encoder.Decode(conn, &rpc)
+		err = peer.codec.Decode(RpcMessage, &rpc)
 		if err != nil {
 			return
 		}
 
 		rpc.From = peer.Addr()
+		if rpc.ReplyTo != 0 {
+			peer.requests.deliver(rpc)
+			continue
+		}
 		t.rpcCh <- rpc
 	}
 }
 
+// secure runs tls handshake on conn and returns encrypted connection with
+// public key of peer.
+func (t *TcpTransport) secure(conn net.Conn, outbound bool) (net.Conn, crypto.PublicKey, error) {
+	var tlsConn *tls.Conn
+	if outbound {
+		tlsConn = tls.Client(conn, t.tlsConfig)
+	} else {
+		tlsConn = tls.Server(conn, t.tlsConfig)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), HandshakeTimeout)
+	defer cancel()
+	if err := tlsConn.HandshakeContext(ctx); err != nil {
+		return conn, nil, err
+	}
+
+	certs := tlsConn.ConnectionState().PeerCertificates
+	if len(certs) == 0 {
+		return conn, nil, ErrInvalidCertificate
+	}
+
+	return tlsConn, certs[0].PublicKey, nil
+}
+
 type TcpPeer struct {
 	net.Conn
 	outbound bool
+	info     PeerInfo
+	requests *requests
 
-	encoder Encoder
+	codec Codec
 }
 
 var _ Peer = (*TcpPeer)(nil)
 
-func NewTcpPeer(conn net.Conn, outbound bool) *TcpPeer {
+func NewTcpPeer(conn net.Conn, outbound bool, codec Codec) *TcpPeer {
 	return &TcpPeer{
 		Conn:     conn,
 		outbound: outbound,
-		encoder:  Encoder{},
+		codec:    codec,
+		requests: newRequests(),
 	}
 }
 
 func (p *TcpPeer) Send(data Rpc) error {
-	encoder := json.NewEncoder(p.Conn)
-
-	if err := encoder.Encode(data); err != nil {
+	if err := p.codec.Encode(RpcMessage, data); err != nil {
 		return fmt.Errorf("failed to encode and send data %+v: %v", data, err)
 	}
 
 	return nil
 }
 
+func (p *TcpPeer) Request(ctx context.Context, method RpcMethod, payload []byte) (Rpc, error) {
+	return p.requests.do(ctx, p.Send, method, payload)
+}
+
 func (p *TcpPeer) Addr() string {
 	return p.Conn.RemoteAddr().String()
 }
+
+func (p *TcpPeer) Info() PeerInfo {
+	return p.info
+}
+
+type tcpHandshakeConn struct {
+	peer *TcpPeer
+	key  crypto.PublicKey
+}
+
+func (c tcpHandshakeConn) PeerKey() crypto.PublicKey {
+	return c.key
+}
+
+func (c tcpHandshakeConn) Send(data any) error {
+	return c.peer.codec.Encode(HandshakeMessage, data)
+}
+
+func (c tcpHandshakeConn) Receive(data any) error {
+	return c.peer.codec.Decode(HandshakeMessage, data)
+}
@@ -0,0 +1,77 @@
+package p2p
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// newTestTcpTransport returns transport listening on random local port,
+// which reports its peers and closed peers to channels.
+func newTestTcpTransport(t *testing.T) (*TcpTransport, chan Peer, chan Peer) {
+	t.Helper()
+
+	transport := NewTcpTransport("127.0.0.1:0")
+	peers, closed := make(chan Peer, 1), make(chan Peer, 1)
+	transport.SetOnPeer(func(peer Peer) error {
+		peers <- peer
+		return nil
+	})
+	transport.SetOnPeerClose(func(peer Peer) { closed <- peer })
+
+	if err := transport.ListenAndAccept(); err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	t.Cleanup(func() { transport.Close() })
+
+	return transport, peers, closed
+}
+
+func waitPeer(t *testing.T, peers chan Peer, event string) {
+	t.Helper()
+
+	select {
+	case <-peers:
+	case <-time.After(time.Second):
+		t.Fatalf("peer is not %s", event)
+	}
+}
+
+func TestTcpTransportCloseDropsPeers(t *testing.T) {
+	tests := []struct {
+		name string
+		// closeDialer closes dialing side instead of accepting one
+		closeDialer bool
+	}{
+		{name: "closing dialing side", closeDialer: true},
+		{name: "closing accepting side", closeDialer: false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			a, aPeers, aClosed := newTestTcpTransport(t)
+			b, bPeers, bClosed := newTestTcpTransport(t)
+
+			if err := a.Dial(b.listener.Addr().String()); err != nil {
+				t.Fatalf("failed to dial: %v", err)
+			}
+			waitPeer(t, aPeers, "connected")
+			waitPeer(t, bPeers, "connected")
+
+			closing, open := b, a
+			if test.closeDialer {
+				closing, open = a, b
+			}
+			if err := closing.Close(); err != nil {
+				t.Fatalf("failed to close transport: %v", err)
+			}
+
+			waitPeer(t, aClosed, "dropped by dialing side")
+			waitPeer(t, bClosed, "dropped by accepting side")
+
+			if err := closing.Dial(open.listener.Addr().String()); !errors.Is(err, ErrTransportClosed) {
+				t.Fatalf("expected %v, got %v", ErrTransportClosed, err)
+			}
+		})
+	}
+}
@@ -1,12 +1,23 @@
 package p2p
 
+import "context"
+
 type Peer interface {
 	Send(Rpc) error
+	// Request sends rpc and waits for response to it until ctx is done
+	Request(ctx context.Context, method RpcMethod, payload []byte) (Rpc, error)
 	Addr() string
+	// Info returns what peer told about itself during handshake
+	Info() PeerInfo
+	Close() error
 }
 
 type Transport interface {
 	SetOnPeer(func(Peer) error)
+	// SetOnPeerClose sets function called once connection of peer accepted
+	// by OnPeer is closed
+	SetOnPeerClose(func(Peer))
+	SetHandshake(HandshakeFunc)
 	Addr() string
 	Dial(string) error
 	ListenAndAccept() error